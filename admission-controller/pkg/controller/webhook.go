@@ -50,8 +50,9 @@ var logLevelMap = map[string]log.Level{
 }
 
 type AccumulatedResult struct {
-	Allow   bool
-	Message string
+	Allow    bool
+	Message  string
+	Warnings []string
 }
 
 func init() {
@@ -148,11 +149,14 @@ func ProcessRequest(req admission.Request) admission.Response {
 	}).Info(ar.Message)
 
 	// return admission response
+	var res admission.Response
 	if ar.Allow {
-		return admission.Allowed(ar.Message)
+		res = admission.Allowed(ar.Message)
 	} else {
-		return admission.Denied(ar.Message)
+		res = admission.Denied(ar.Message)
 	}
+	res.Warnings = ar.Warnings
+	return res
 }
 
 func loadAdmissionControllerConfig() (*acconfig.AdmissionControllerConfig, error) {
@@ -200,6 +204,9 @@ func getAccumulatedResult(results []shield.ResultFromRequestHandler) *Accumulate
 	allowMessages := []string{}
 	accumulatedRes := &AccumulatedResult{}
 	for _, result := range results {
+		for _, w := range result.Warnings {
+			accumulatedRes.Warnings = append(accumulatedRes.Warnings, "["+result.Profile+"]"+w)
+		}
 		if !result.Allow {
 			msg := "[" + result.Profile + "]" + result.Message
 			denyMessages = append(denyMessages, msg)
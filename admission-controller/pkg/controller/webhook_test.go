@@ -0,0 +1,46 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package controller
+
+import (
+	"testing"
+
+	"github.com/IBM/integrity-shield/integrity-shield-server/pkg/shield"
+)
+
+func TestGetAccumulatedResultWithWarnings(t *testing.T) {
+	results := []shield.ResultFromRequestHandler{
+		{
+			Allow:    true,
+			Message:  "singed by a valid signer: signer@signer.com",
+			Profile:  "profile-a",
+			Warnings: []string{"image not pinned by digest: nginx:1.21"},
+		},
+		{
+			Allow:   true,
+			Message: "not protected",
+			Profile: "profile-b",
+		},
+	}
+	ar := getAccumulatedResult(results)
+	if !ar.Allow {
+		t.Error("request should be allowed")
+	}
+	if len(ar.Warnings) != 1 || ar.Warnings[0] != "[profile-a]image not pinned by digest: nginx:1.21" {
+		t.Errorf("unexpected warnings: %v", ar.Warnings)
+	}
+}
@@ -59,6 +59,8 @@ type IntegrityShieldSpec struct {
 	AdmissionControllerConfigKey  string              `json:"admissionControllerConfigKey,omitempty"`
 	AdmissionControllerConfigName string              `json:"admissionControllerConfigName,omitempty"`
 	AdmissionControllerConfig     string              `json:"admissionControllerConfig,omitempty"`
+	// integrity shield audits requests instead of denying them while this is true
+	EnforcementDisabled bool `json:"enforcementDisabled,omitempty"`
 
 	// observer
 	Observer Observer `json:"observer,omitempty"`
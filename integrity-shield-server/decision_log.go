@@ -0,0 +1,92 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/IBM/integrity-shield/integrity-shield-server/pkg/shield"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// decision log writes each admission decision as a line of compact JSON, independent of the log level and format
+const decisionLogEnvKey = "DECISION_LOG_ENABLED"
+
+var decisionLogWriter io.Writer = os.Stdout
+var decisionLogMutex sync.Mutex
+
+// decisionRecord is the schema of the decision log. Fields are always written, even if empty,
+// so that collectors can rely on them.
+type decisionRecord struct {
+	Time       string `json:"time"`
+	UID        string `json:"uid"`
+	Operation  string `json:"operation"`
+	Group      string `json:"group"`
+	Version    string `json:"version"`
+	Kind       string `json:"kind"`
+	Namespace  string `json:"namespace"`
+	Name       string `json:"name"`
+	UserName   string `json:"userName"`
+	Constraint string `json:"constraint"`
+	Allow      bool   `json:"allow"`
+	Reason     string `json:"reason"`
+	Message    string `json:"message"`
+}
+
+func isDecisionLogEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv(decisionLogEnvKey))
+	return enabled
+}
+
+func newDecisionRecord(req admission.Request, parameters *k8smnfconfig.ParameterObject, result *shield.ResultFromRequestHandler, now time.Time) decisionRecord {
+	return decisionRecord{
+		Time:       now.UTC().Format(time.RFC3339),
+		UID:        string(req.UID),
+		Operation:  string(req.Operation),
+		Group:      req.Kind.Group,
+		Version:    req.Kind.Version,
+		Kind:       req.Kind.Kind,
+		Namespace:  req.Namespace,
+		Name:       req.Name,
+		UserName:   req.UserInfo.Username,
+		Constraint: parameters.ConstraintName,
+		Allow:      result.Allow,
+		Reason:     result.Reason,
+		Message:    result.Message,
+	}
+}
+
+// writeDecision writes the decision record as a single line.
+func writeDecision(w io.Writer, record decisionRecord) {
+	recordBytes, err := json.Marshal(record)
+	if err != nil {
+		log.Errorf("failed to marshal the decision record; %s", err.Error())
+		return
+	}
+	decisionLogMutex.Lock()
+	defer decisionLogMutex.Unlock()
+	if _, err := w.Write(append(recordBytes, '\n')); err != nil {
+		log.Errorf("failed to write the decision record; %s", err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/IBM/integrity-shield/integrity-shield-server/pkg/shield"
+	admv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestWriteDecision(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	req := admission.Request{AdmissionRequest: admv1.AdmissionRequest{
+		UID:       "705ab4f5-6393-11e8-b7cc-42010a800002",
+		Kind:      metav1.GroupVersionKind{Group: "", Version: "v1", Kind: "ConfigMap"},
+		Namespace: "sample-ns",
+		Name:      "sample-cm",
+		Operation: admv1.Create,
+	}}
+	req.UserInfo.Username = "sample-user"
+	parameters := &k8smnfconfig.ParameterObject{ConstraintName: "configmap-constraint"}
+
+	var buf bytes.Buffer
+	writeDecision(&buf, newDecisionRecord(req, parameters, &shield.ResultFromRequestHandler{Allow: true, Message: "singed by a valid signer: sample-signer"}, now))
+	writeDecision(&buf, newDecisionRecord(req, parameters, &shield.ResultFromRequestHandler{Allow: false, Message: "signature is too old", Reason: shield.ReasonStaleSignature}, now))
+
+	expected := `{"time":"2021-09-01T12:00:00Z","uid":"705ab4f5-6393-11e8-b7cc-42010a800002","operation":"CREATE","group":"","version":"v1","kind":"ConfigMap","namespace":"sample-ns","name":"sample-cm","userName":"sample-user","constraint":"configmap-constraint","allow":true,"reason":"","message":"singed by a valid signer: sample-signer"}
+{"time":"2021-09-01T12:00:00Z","uid":"705ab4f5-6393-11e8-b7cc-42010a800002","operation":"CREATE","group":"","version":"v1","kind":"ConfigMap","namespace":"sample-ns","name":"sample-cm","userName":"sample-user","constraint":"configmap-constraint","allow":false,"reason":"STALE_SIGNATURE","message":"signature is too old"}
+`
+	if buf.String() != expected {
+		t.Errorf("unexpected decision log:\n%s", buf.String())
+	}
+}
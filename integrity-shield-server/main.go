@@ -22,10 +22,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"os"
 	"path"
+	"time"
 
 	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
 	"github.com/IBM/integrity-shield/integrity-shield-server/pkg/shield"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
@@ -107,6 +110,9 @@ func requestHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	result := shield.RequestHandler(*request, parameters)
+	if isDecisionLogEnabled() {
+		writeDecision(decisionLogWriter, newDecisionRecord(*request, parameters, result, time.Now()))
+	}
 	resp, err := json.Marshal(result)
 	if err != nil {
 		http.Error(w, fmt.Sprintf("marshaling request handler result: %v", err), http.StatusInternalServerError)
@@ -126,10 +132,20 @@ func checkLiveness(w http.ResponseWriter, r *http.Request) {
 
 func checkReadiness(w http.ResponseWriter, r *http.Request) {
 	msg := "readiness ok"
+	if hash := shield.GetActiveConfigHash(); hash != "" {
+		msg = fmt.Sprintf("%s; config hash: %s", msg, hash)
+	}
 	_, _ = w.Write([]byte(msg))
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "selftest" {
+		if !runSelfTest(defaultSelfTestChecks(), os.Stdout) {
+			os.Exit(1)
+		}
+		return
+	}
+
 	tlsCertPath := path.Join(tlsDir, tlsCertFile)
 	tlsKeyPath := path.Join(tlsDir, tlsKeyFile)
 
@@ -139,18 +155,22 @@ func main() {
 		panic(fmt.Sprintf("unable to load certs: %v", err))
 	}
 
+	clientCAFile := os.Getenv(clientCAFileEnvKey)
+	tlsConfig, err := newServerTLSConfig(pair, clientCAFile)
+	if err != nil {
+		panic(fmt.Sprintf("unable to configure tls: %v", err))
+	}
+	mtlsEnabled := clientCAFile != ""
+
 	mux := http.NewServeMux()
 
-	mux.HandleFunc("/api", defaultHandler)
-	mux.HandleFunc("/api/request", requestHandler)
+	mux.HandleFunc("/api", requireClientCert(defaultHandler, mtlsEnabled))
+	mux.HandleFunc("/api/request", requireClientCert(requestHandler, mtlsEnabled))
 	mux.HandleFunc("/health/liveness", checkLiveness)
 	mux.HandleFunc("/health/readiness", checkReadiness)
+	mux.Handle("/metrics", promhttp.Handler())
 
-	serverObj := &http.Server{
-		Addr:      ":8080",
-		TLSConfig: &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12},
-		Handler:   mux,
-	}
+	serverObj := newServer(":8080", mux, tlsConfig)
 
 	if err := serverObj.ListenAndServeTLS("", ""); err != nil {
 		panic(fmt.Sprintf("Fail to run integrity shield api server: %v", err))
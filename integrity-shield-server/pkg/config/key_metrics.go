@@ -0,0 +1,53 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var keyLoadFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "integrityshield_key_load_failures_total",
+	Help: "Number of failures to load a key from the secret.",
+}, []string{"secret"})
+
+var keyLoadLastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "integrityshield_key_load_last_success_timestamp",
+	Help: "Unix time when the key was loaded from the secret successfully last.",
+}, []string{"secret"})
+
+func init() {
+	prometheus.MustRegister(keyLoadFailures, keyLoadLastSuccess)
+}
+
+// recordKeyLoad updates the metrics of key loading. The label is the secret reference `<namespace>/<name>`,
+// and the key material is never logged.
+func recordKeyLoad(namespace, name string, err error, now time.Time) {
+	secret := fmt.Sprintf("%s/%s", namespace, name)
+	if err != nil {
+		keyLoadFailures.WithLabelValues(secret).Inc()
+		log.WithFields(log.Fields{
+			"secret": secret,
+		}).Errorf("failed to load a key from the secret; %s", err.Error())
+		return
+	}
+	keyLoadLastSuccess.WithLabelValues(secret).Set(float64(now.Unix()))
+}
@@ -0,0 +1,63 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"os"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestLoadKeySecretMetrics(t *testing.T) {
+	orgFunc := getSecretResource
+	defer func() { getSecretResource = orgFunc }()
+
+	// RBAC error
+	getSecretResource = func(apiVersion, kind, namespace, name string) (*unstructured.Unstructured, error) {
+		return nil, errors.New("secrets \"keyring-secret\" is forbidden")
+	}
+	before := testutil.ToFloat64(keyLoadFailures.WithLabelValues("test-ns/keyring-secret"))
+	if _, err := LoadKeySecret("test-ns", "keyring-secret"); err == nil {
+		t.Error("loading a key should fail")
+	}
+	if count := testutil.ToFloat64(keyLoadFailures.WithLabelValues("test-ns/keyring-secret")); count != before+1 {
+		t.Errorf("failure counter should increment: %v", count)
+	}
+
+	// success
+	getSecretResource = func(apiVersion, kind, namespace, name string) (*unstructured.Unstructured, error) {
+		secret := &unstructured.Unstructured{}
+		secret.SetAPIVersion("v1")
+		secret.SetKind("Secret")
+		secret.SetNamespace(namespace)
+		secret.SetName(name)
+		// "cosign.pub" in base64
+		_ = unstructured.SetNestedField(secret.Object, "Y29zaWduLnB1Yg==", "data", "cosign.pub")
+		return secret, nil
+	}
+	defer os.RemoveAll("/tmp/test-ns-metrics")
+	keyPath, err := LoadKeySecret("test-ns-metrics", "keyring-secret")
+	if err != nil || keyPath == "" {
+		t.Errorf("loading a key should succeed: %v", err)
+	}
+	if ts := testutil.ToFloat64(keyLoadLastSuccess.WithLabelValues("test-ns-metrics/keyring-secret")); ts == 0 {
+		t.Error("last success time should be set")
+	}
+}
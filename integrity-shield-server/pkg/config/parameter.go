@@ -41,9 +41,12 @@ type KeyConfig struct {
 
 type ObjectUserBindingList []ObjectUserBinding
 
+// ObjectUserBinding matches a request for the objects by a user in Users or by a user who belongs to any of Groups.
+// Both Users and Groups support glob patterns such as `system:serviceaccount:*`.
 type ObjectUserBinding struct {
 	Objects k8smanifest.ObjectReferenceList `json:"objects,omitempty"`
 	Users   []string                        `json:"users,omitempty"`
+	Groups  []string                        `json:"groups,omitempty"`
 }
 
 type ImageProfile struct {
@@ -53,21 +56,26 @@ func (p *ParameterObject) DeepCopyInto(p2 *ParameterObject) {
 	copier.Copy(&p2, &p)
 }
 
-func (u ObjectUserBinding) Match(obj unstructured.Unstructured, username string) bool {
+func (u ObjectUserBinding) Match(obj unstructured.Unstructured, username string, groups []string) bool {
 	if u.Objects.Match(obj) {
 		if k8smnfutil.MatchWithPatternArray(username, u.Users) {
 			return true
 		}
+		for _, group := range groups {
+			if k8smnfutil.MatchWithPatternArray(group, u.Groups) {
+				return true
+			}
+		}
 	}
 	return false
 }
 
-func (l ObjectUserBindingList) Match(obj unstructured.Unstructured, username string) bool {
+func (l ObjectUserBindingList) Match(obj unstructured.Unstructured, username string, groups []string) bool {
 	if len(l) == 0 {
 		return false
 	}
 	for _, u := range l {
-		if u.Match(obj, username) {
+		if u.Match(obj, username, groups) {
 			return true
 		}
 	}
@@ -0,0 +1,59 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"testing"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestObjectUserBindingListMatchByGroup(t *testing.T) {
+	obj := unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace("sample-ns")
+	obj.SetName("sample-cm")
+
+	skipUsers := ObjectUserBindingList{
+		{
+			Objects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap"}},
+			Users:   []string{"system:admin"},
+			Groups:  []string{"system:masters", "platform-*"},
+		},
+	}
+	if !skipUsers.Match(obj, "alice", []string{"system:authenticated", "system:masters"}) {
+		t.Error("user in a listed group should match")
+	}
+	if !skipUsers.Match(obj, "bob", []string{"platform-admins"}) {
+		t.Error("user in a group matching the pattern should match")
+	}
+	if !skipUsers.Match(obj, "system:admin", nil) {
+		t.Error("listed user should match without groups")
+	}
+	if skipUsers.Match(obj, "carol", []string{"system:authenticated", "developers"}) {
+		t.Error("user not in the listed groups should not match")
+	}
+
+	// groups are checked only for the objects of the binding
+	secret := *obj.DeepCopy()
+	secret.SetKind("Secret")
+	if skipUsers.Match(secret, "alice", []string{"system:masters"}) {
+		t.Error("binding should not match objects other than its objects")
+	}
+}
@@ -27,9 +27,11 @@ import (
 
 	"github.com/pkg/errors"
 	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	k8smnfutil "github.com/sigstore/k8s-manifest-sigstore/pkg/util"
 	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/kubeutil"
 	log "github.com/sirupsen/logrus"
 	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
 )
 
@@ -48,25 +50,222 @@ var logLevelMap = map[string]log.Level{
 type RequestHandlerConfig struct {
 	ImageVerificationConfig ImageVerificationConfig `json:"imageVerificationConfig,omitempty"`
 	KeyPathList             []string                `json:"keyPathList,omitempty"`
+	NamespacedKeyPathList   []NamespacedKeyPaths    `json:"namespacedKeyPathList,omitempty"`
+	KeyValidityList         []KeyValidity           `json:"keyValidityList,omitempty"`
+	KeyExpiryWarningPeriod  metav1.Duration         `json:"keyExpiryWarningPeriod,omitempty"`
 	SigStoreConfig          SigStoreConfig          `json:"sigStoreConfig,omitempty"`
 	RequestFilterProfile    RequestFilterProfile    `json:"requestFilterProfile,omitempty"`
 	Log                     LogConfig               `json:"log,omitempty"`
 	SideEffectConfig        SideEffectConfig        `json:"sideEffect,omitempty"`
+	VerifyTimeout           metav1.Duration         `json:"verifyTimeout,omitempty"`
+	WarningConfig           WarningConfig           `json:"warning,omitempty"`
+	OverlayConfig           OverlayConfig           `json:"overlay,omitempty"`
+	EnforcementCutoff       *metav1.Time            `json:"enforcementCutoff,omitempty"`
+	EnforcementConfig       EnforcementConfig       `json:"enforcement,omitempty"`
+	FailurePolicy           string                  `json:"failurePolicy,omitempty"`
+	OperationRules          []OperationRule         `json:"operationRules,omitempty"`
+	ManifestRefAnnotation   string                  `json:"manifestRefAnnotation,omitempty"`
+	DefaultPosture          string                  `json:"defaultPosture,omitempty"`
+	Placeholders            []PlaceholderPattern    `json:"placeholders,omitempty"`
+	DigestVerification      bool                    `json:"digestVerification,omitempty"`
+	MaxSignatureAge         metav1.Duration         `json:"maxSignatureAge,omitempty"`
+	CircuitBreaker          CircuitBreakerConfig    `json:"circuitBreaker,omitempty"`
+	ClockSkewAllowance      metav1.Duration         `json:"clockSkewAllowance,omitempty"`
+	UpdateVerification      string                  `json:"updateVerification,omitempty"`
+	ManifestRefTemplate     string                  `json:"manifestRefTemplate,omitempty"`
+	RequireAllKeys          bool                    `json:"requireAllKeys,omitempty"`
+	DisableCanonicalization bool                    `json:"disableCanonicalization,omitempty"`
+	UnorderedLists          []UnorderedList         `json:"unorderedLists,omitempty"`
 	Options                 []string
 }
 
+// NamespacedKeyPaths is a list of keys used only for the resources in the namespaces
+type NamespacedKeyPaths struct {
+	Namespaces  []string `json:"namespaces,omitempty"`
+	KeyPathList []string `json:"keyPathList,omitempty"`
+}
+
+// KeyValidity is the validity window of a key. Signatures verified only by keys outside
+// the window are rejected. The key is specified by KeyPath, or by the secret of the key in keyConfigs.
+type KeyValidity struct {
+	KeyPath            string       `json:"keyPath,omitempty"`
+	KeySecretName      string       `json:"keySecretName,omitempty"`
+	KeySecretNamespace string       `json:"keySecretNamespace,omitempty"`
+	NotBefore          *metav1.Time `json:"notBefore,omitempty"`
+	NotAfter           *metav1.Time `json:"notAfter,omitempty"`
+}
+
 type LogConfig struct {
 	Level                    string `json:"level,omitempty"`
 	ManifestSigstoreLogLevel string `json:"manifestSigstoreLogLevel,omitempty"`
 	Format                   string `json:"format,omitempty"`
+	// fields redacted when request objects are logged; data and stringData of Secret are always redacted
+	RedactFields k8smanifest.ObjectFieldBindingList `json:"redactFields,omitempty"`
 }
 
 type SideEffectConfig struct {
 	// Event
 	CreateDenyEvent bool `json:"createDenyEvent"`
+	// Identical denials within this window are aggregated into one event.
+	// They are always aggregated if it is not set.
+	DenyEventAggregationWindow metav1.Duration `json:"denyEventAggregationWindow,omitempty"`
+	// Events are created in this namespace instead of the namespace of the resource if it is set.
+	DenyEventNamespace string `json:"denyEventNamespace,omitempty"`
+	// Type of the event, e.g. `Warning` or `Normal`
+	DenyEventType string `json:"denyEventType,omitempty"`
+	// Go template of the reason; `{{.Namespace}}`, `{{.Name}}`, `{{.Kind}}` and `{{.Operation}}` can be used
+	DenyEventReason string `json:"denyEventReason,omitempty"`
+	// Namespace annotation
+	AnnotateNamespaceOnDeny  bool            `json:"annotateNamespaceOnDeny,omitempty"`
+	NamespaceAnnotationLimit metav1.Duration `json:"namespaceAnnotationLimit,omitempty"`
+}
+
+// WarningConfig enables soft policies. A violation of a soft policy does not deny the request,
+// but it is returned as a warning of the admission response.
+type WarningConfig struct {
+	ImageNotPinnedByDigest bool `json:"imageNotPinnedByDigest,omitempty"`
+}
+
+// OverlayConfig tolerates the fields which differ between environments, e.g. replicas in prod,
+// so that the same signed base manifest is verified in all environments.
+// The environment of a request is the value of the label `NamespaceLabelKey` of its namespace.
+type OverlayConfig struct {
+	NamespaceLabelKey string                                        `json:"namespaceLabelKey,omitempty"`
+	Profiles          map[string]k8smanifest.ObjectFieldBindingList `json:"profiles,omitempty"`
+}
+
+// GetIgnoreFields returns the overlay fields for the environment of the namespace labels.
+func (c OverlayConfig) GetIgnoreFields(nsLabels map[string]string) k8smanifest.ObjectFieldBindingList {
+	if c.NamespaceLabelKey == "" || len(c.Profiles) == 0 {
+		return nil
+	}
+	env, found := nsLabels[c.NamespaceLabelKey]
+	if !found {
+		return nil
+	}
+	return c.Profiles[env]
+}
+
+const (
+	OperationActionAllow  = "allow"
+	OperationActionDeny   = "deny"
+	OperationActionVerify = "verify"
+)
+
+// OperationRule decides the action for requests of the operations and the subresources,
+// e.g. deny CONNECT to `pods/exec`. An empty list matches any value.
+type OperationRule struct {
+	Operations   []string `json:"operations,omitempty"`
+	SubResources []string `json:"subResources,omitempty"`
+	Action       string   `json:"action"`
+}
+
+func (r OperationRule) Match(operation, subResource string) bool {
+	if len(r.Operations) != 0 && !k8smnfutil.MatchWithPatternArray(operation, r.Operations) {
+		return false
+	}
+	if len(r.SubResources) != 0 && !k8smnfutil.MatchWithPatternArray(subResource, r.SubResources) {
+		return false
+	}
+	return true
+}
+
+// PlaceholderPattern is a placeholder in signed manifests which a controller resolves at apply time.
+// The resolved value in a requested object is treated as matching the placeholder if the whole value
+// matches Pattern (regular expression).
+type PlaceholderPattern struct {
+	Placeholder string `json:"placeholder,omitempty"`
+	Pattern     string `json:"pattern,omitempty"`
+}
+
+// UnorderedList is a list in objects whose elements may be reordered by controllers, e.g. env vars.
+// Path is the list field like `spec.template.spec.containers.*.env`, and elements are matched by the value of Key.
+type UnorderedList struct {
+	Path string `json:"path,omitempty"`
+	Key  string `json:"key,omitempty"`
+}
+
+// CircuitBreakerConfig trips the circuit breaker around the verification backend after FailureThreshold
+// consecutive failures. While it is open, requests are decided by FailurePolicy without verification
+// until Cooldown (default 30s) passes. It is disabled if FailureThreshold is 0.
+type CircuitBreakerConfig struct {
+	FailureThreshold int             `json:"failureThreshold,omitempty"`
+	Cooldown         metav1.Duration `json:"cooldown,omitempty"`
+}
+
+// UpdateVerification decides which UPDATE requests are verified. With "OnChange" (default), an UPDATE which changes
+// only ignore fields, status or metadata set by the API server is allowed without verification.
+// With "Always", every UPDATE is verified.
+const (
+	UpdateVerificationOnChange = "OnChange"
+	UpdateVerificationAlways   = "Always"
+)
+
+// DefaultPosture decides the response for an in-scope resource without any signature.
+// "deny" (default) requires every in-scope resource to be signed, and "allow" denies only
+// explicit verification failures such as a diff from the signed manifest.
+const (
+	DefaultPostureDeny  = "deny"
+	DefaultPostureAllow = "allow"
+)
+
+// FailurePolicy decides the response when a request cannot be processed, e.g. the object cannot be decoded.
+// "Fail" (default) denies the request, and "Ignore" allows it.
+const (
+	FailurePolicyFail   = "Fail"
+	FailurePolicyIgnore = "Ignore"
+)
+
+const (
+	EnforcementLevelEnforce = "enforce"
+	EnforcementLevelAudit   = "audit"
+)
+
+// EnforcementConfig sets the enforcement level for each kind. A request denied at the "audit" level
+// is allowed and only logged. DefaultLevel is used for the kinds not listed, and it is "enforce" if empty.
+type EnforcementConfig struct {
+	DefaultLevel string                 `json:"defaultLevel,omitempty"`
+	Levels       []KindEnforcementLevel `json:"levels,omitempty"`
+}
+
+type KindEnforcementLevel struct {
+	Kinds []metav1.GroupVersionKind `json:"kinds,omitempty"`
+	Level string                    `json:"level,omitempty"`
+}
+
+// GetLevel returns the enforcement level of the first entry which matches the kind.
+func (c EnforcementConfig) GetLevel(kind metav1.GroupVersionKind) string {
+	for _, l := range c.Levels {
+		for _, k := range l.Kinds {
+			if matchGroupVersionKind(k, kind) {
+				return l.Level
+			}
+		}
+	}
+	if c.DefaultLevel == "" {
+		return EnforcementLevelEnforce
+	}
+	return c.DefaultLevel
+}
+
+// matchGroupVersionKind matches each of group, version and kind as a pattern. An empty field matches any value.
+func matchGroupVersionKind(pattern, kind metav1.GroupVersionKind) bool {
+	if pattern.Group != "" && !k8smnfutil.MatchSinglePattern(pattern.Group, kind.Group) {
+		return false
+	}
+	if pattern.Version != "" && !k8smnfutil.MatchSinglePattern(pattern.Version, kind.Version) {
+		return false
+	}
+	if pattern.Kind != "" && !k8smnfutil.MatchSinglePattern(pattern.Kind, kind.Kind) {
+		return false
+	}
+	return true
 }
 
 type ImageVerificationConfig struct {
+	// RequiredSignatures is the number of distinct keys which must have signed the manifest.
+	// If it is more than 1, the resource is verified with each key separately.
+	RequiredSignatures int `json:"requiredSignatures,omitempty"`
 }
 
 type SigStoreConfig struct {
@@ -76,6 +275,217 @@ type RequestFilterProfile struct {
 	SkipObjects  k8smanifest.ObjectReferenceList    `json:"skipObjects,omitempty"`
 	SkipUsers    ObjectUserBindingList              `json:"skipUsers,omitempty"`
 	IgnoreFields k8smanifest.ObjectFieldBindingList `json:"ignoreFields,omitempty"`
+	ApiGroups    ApiGroupFilter                     `json:"apiGroups,omitempty"`
+	// label and annotation keys which are stripped before comparison, e.g. `argocd.argoproj.io/*`
+	StripMetadataKeys []string `json:"stripMetadataKeys,omitempty"`
+	// objects owned by a controller, e.g. Pods of a ReplicaSet, are not verified; the owner is trusted to be verified
+	SkipOwnedObjects bool `json:"skipOwnedObjects,omitempty"`
+	// users of the controllers which create owned objects, e.g. `system:serviceaccount:kube-system:replicaset-controller`;
+	// owned objects are skipped only in requests by them. Glob patterns are supported.
+	OwnedObjectControllers []string `json:"ownedObjectControllers,omitempty"`
+	// immutable fields assigned by the API server, ignored on UPDATE; the default set is used if not set
+	ServerAssignedFields k8smanifest.ObjectFieldBindingList `json:"serverAssignedFields,omitempty"`
+	// metadata fields populated by the API server for all objects, stripped in both admission and observation;
+	// the default set is used if not set. managedFields are stripped even if they are not listed.
+	ServerManagedFields []string `json:"serverManagedFields,omitempty"`
+}
+
+// defaultServerManagedFields is a set of metadata fields which the API server populates on every object.
+// They change on every write and never match signed manifests.
+var defaultServerManagedFields = []string{
+	"metadata.managedFields",
+	"metadata.managedFields.*",
+	"metadata.resourceVersion",
+	"metadata.uid",
+	"metadata.generation",
+	"metadata.creationTimestamp",
+	"metadata.selfLink",
+}
+
+// managedFieldsIgnoreFields are always stripped; the field managers recorded by server-side apply
+// are never part of signed manifests.
+var managedFieldsIgnoreFields = []string{
+	"metadata.managedFields",
+	"metadata.managedFields.*",
+}
+
+// defaultServerAssignedFields is a set of immutable fields which the API server or controllers assign on creation.
+// They are never part of signed manifests, and a resubmitted object on UPDATE keeps the assigned values.
+var defaultServerAssignedFields = k8smanifest.ObjectFieldBindingList{
+	{
+		Fields: []string{
+			"spec.clusterIP",
+			"spec.clusterIPs",
+			"spec.clusterIPs.*",
+			"spec.ipFamilies",
+			"spec.ipFamilies.*",
+			"spec.ipFamilyPolicy",
+		},
+		Objects: k8smanifest.ObjectReferenceList{{Kind: "Service"}},
+	},
+	{
+		Fields:  []string{"spec.nodeName"},
+		Objects: k8smanifest.ObjectReferenceList{{Kind: "Pod"}},
+	},
+	{
+		Fields:  []string{"spec.volumeName"},
+		Objects: k8smanifest.ObjectReferenceList{{Kind: "PersistentVolumeClaim"}},
+	},
+}
+
+// GetServerAssignedFields returns the configured server-assigned fields, or the default set if they are not configured.
+// An empty list disables ignoring them.
+func (p RequestFilterProfile) GetServerAssignedFields() k8smanifest.ObjectFieldBindingList {
+	if p.ServerAssignedFields == nil {
+		return defaultServerAssignedFields
+	}
+	return p.ServerAssignedFields
+}
+
+// GetServerManagedIgnoreFields returns the server-managed fields as ignore fields for all objects.
+// The default set is used if they are not configured, and an empty list disables stripping them
+// except managedFields, which are always stripped.
+func (p RequestFilterProfile) GetServerManagedIgnoreFields() k8smanifest.ObjectFieldBindingList {
+	configured := p.ServerManagedFields
+	if configured == nil {
+		configured = defaultServerManagedFields
+	}
+	fields := dedupStrings(append(append([]string{}, configured...), managedFieldsIgnoreFields...))
+	return k8smanifest.ObjectFieldBindingList{
+		{
+			Fields:  fields,
+			Objects: k8smanifest.ObjectReferenceList{k8smanifest.ObjectReference{Name: "*"}},
+		},
+	}
+}
+
+// GetStripMetadataIgnoreFields converts StripMetadataKeys into ignore fields of labels and annotations for all objects.
+func (p RequestFilterProfile) GetStripMetadataIgnoreFields() k8smanifest.ObjectFieldBindingList {
+	if len(p.StripMetadataKeys) == 0 {
+		return nil
+	}
+	fields := []string{}
+	for _, key := range p.StripMetadataKeys {
+		fields = append(fields, "metadata.labels."+key, "metadata.annotations."+key)
+	}
+	return k8smanifest.ObjectFieldBindingList{
+		{
+			Fields:  fields,
+			Objects: k8smanifest.ObjectReferenceList{k8smanifest.ObjectReference{Name: "*"}},
+		},
+	}
+}
+
+// Merge returns a profile which combines the profile with fragments maintained separately, e.g. by each team.
+// Entries of the skip lists, the ignore-field lists, StripMetadataKeys and ApiGroups.Exclude are concatenated
+// in order and exact duplicates are removed. Overlapping entries are all kept, because an entry of these lists
+// takes effect if any of them matches. ApiGroups.Include, SkipOwnedObjects, OwnedObjectControllers,
+// ServerAssignedFields and ServerManagedFields are taken only from the base profile, so that fragments cannot widen them.
+func (p RequestFilterProfile) Merge(fragments ...RequestFilterProfile) RequestFilterProfile {
+	merged := p
+	for _, f := range fragments {
+		merged.SkipObjects = append(merged.SkipObjects, f.SkipObjects...)
+		merged.SkipUsers = append(merged.SkipUsers, f.SkipUsers...)
+		merged.IgnoreFields = append(merged.IgnoreFields, f.IgnoreFields...)
+		merged.StripMetadataKeys = append(merged.StripMetadataKeys, f.StripMetadataKeys...)
+		merged.ApiGroups.Exclude = append(merged.ApiGroups.Exclude, f.ApiGroups.Exclude...)
+	}
+	merged.SkipObjects = dedupObjectReferences(merged.SkipObjects)
+	merged.SkipUsers = dedupObjectUserBindings(merged.SkipUsers)
+	merged.IgnoreFields = dedupObjectFieldBindings(merged.IgnoreFields)
+	merged.StripMetadataKeys = dedupStrings(merged.StripMetadataKeys)
+	merged.ApiGroups.Exclude = dedupStrings(merged.ApiGroups.Exclude)
+	return merged
+}
+
+func dedupObjectReferences(l k8smanifest.ObjectReferenceList) k8smanifest.ObjectReferenceList {
+	var deduped k8smanifest.ObjectReferenceList
+	found := map[string]bool{}
+	for _, e := range l {
+		key := getDedupKey(e)
+		if !found[key] {
+			found[key] = true
+			deduped = append(deduped, e)
+		}
+	}
+	return deduped
+}
+
+func dedupObjectUserBindings(l ObjectUserBindingList) ObjectUserBindingList {
+	var deduped ObjectUserBindingList
+	found := map[string]bool{}
+	for _, e := range l {
+		key := getDedupKey(e)
+		if !found[key] {
+			found[key] = true
+			deduped = append(deduped, e)
+		}
+	}
+	return deduped
+}
+
+func dedupObjectFieldBindings(l k8smanifest.ObjectFieldBindingList) k8smanifest.ObjectFieldBindingList {
+	var deduped k8smanifest.ObjectFieldBindingList
+	found := map[string]bool{}
+	for _, e := range l {
+		key := getDedupKey(e)
+		if !found[key] {
+			found[key] = true
+			deduped = append(deduped, e)
+		}
+	}
+	return deduped
+}
+
+func dedupStrings(l []string) []string {
+	var deduped []string
+	found := map[string]bool{}
+	for _, e := range l {
+		if !found[e] {
+			found[e] = true
+			deduped = append(deduped, e)
+		}
+	}
+	return deduped
+}
+
+func getDedupKey(e interface{}) string {
+	b, _ := json.Marshal(e)
+	return string(b)
+}
+
+// ApiGroupFilter limits verification to the API groups. Patterns such as `*.example.com` can be used,
+// and the core group is matched as `core`. All groups are included if Include is empty.
+type ApiGroupFilter struct {
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+}
+
+const CoreApiGroupName = "core"
+
+// Match returns true if the API group is verified.
+func (f ApiGroupFilter) Match(group string) bool {
+	if group == "" {
+		group = CoreApiGroupName
+	}
+	if len(f.Include) != 0 && !k8smnfutil.MatchWithPatternArray(group, f.Include) {
+		return false
+	}
+	if len(f.Exclude) != 0 && k8smnfutil.MatchWithPatternArray(group, f.Exclude) {
+		return false
+	}
+	return true
+}
+
+// GetNamespacedKeyPathList returns the keys of the first namespaced key list which matches the namespace.
+// If found, only these keys are used for the namespace instead of the global KeyPathList and the keys of the constraint.
+func (c *RequestHandlerConfig) GetNamespacedKeyPathList(namespace string) ([]string, bool) {
+	for _, nk := range c.NamespacedKeyPathList {
+		if k8smnfutil.MatchWithPatternArray(namespace, nk.Namespaces) {
+			return nk.KeyPathList, true
+		}
+	}
+	return nil, false
 }
 
 func SetupLogger(config LogConfig, req admission.Request) {
@@ -103,15 +513,31 @@ func SetupLogger(config LogConfig, req admission.Request) {
 	}
 }
 
+// getSecretResource can be replaced in tests
+var getSecretResource = kubeutil.GetResource
+
+// GetKeySecretDir returns the directory where the key files in the secret are saved.
+func GetKeySecretDir(keySecretNamespace, keySecretName string) string {
+	return fmt.Sprintf("/tmp/%s/%s/", keySecretNamespace, keySecretName)
+}
+
+// LoadKeySecret saves the key in the secret as a file and returns its path.
+// Failures are logged with the secret reference and counted in the metric.
 func LoadKeySecret(keySecretNamespace, keySecretName string) (string, error) {
-	obj, err := kubeutil.GetResource("v1", "Secret", keySecretNamespace, keySecretName)
+	keyPath, err := loadKeySecret(keySecretNamespace, keySecretName)
+	recordKeyLoad(keySecretNamespace, keySecretName, err, time.Now())
+	return keyPath, err
+}
+
+func loadKeySecret(keySecretNamespace, keySecretName string) (string, error) {
+	obj, err := getSecretResource("v1", "Secret", keySecretNamespace, keySecretName)
 	if err != nil {
 		return "", errors.Wrap(err, fmt.Sprintf("failed to get a secret `%s` in `%s` namespace", keySecretName, keySecretNamespace))
 	}
 	objBytes, _ := json.Marshal(obj.Object)
 	var secret v1.Secret
 	_ = json.Unmarshal(objBytes, &secret)
-	keyDir := fmt.Sprintf("/tmp/%s/%s/", keySecretNamespace, keySecretName)
+	keyDir := GetKeySecretDir(keySecretNamespace, keySecretName)
 	sumErr := []string{}
 	keyPath := ""
 	for fname, keyData := range secret.Data {
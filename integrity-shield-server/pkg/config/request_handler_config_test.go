@@ -0,0 +1,150 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package config
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestApiGroupFilterMatch(t *testing.T) {
+	filter := ApiGroupFilter{
+		Include: []string{"apps", "networking.k8s.io", "*.example.com", "core"},
+		Exclude: []string{"internal.example.com"},
+	}
+	for _, group := range []string{"apps", "networking.k8s.io", "widgets.example.com", ""} {
+		if !filter.Match(group) {
+			t.Errorf("`%s` should be included", group)
+		}
+	}
+	for _, group := range []string{"batch", "rbac.authorization.k8s.io", "internal.example.com"} {
+		if filter.Match(group) {
+			t.Errorf("`%s` should be skipped", group)
+		}
+	}
+	if !(ApiGroupFilter{}).Match("batch") {
+		t.Error("all groups should be included by default")
+	}
+}
+
+func TestServerAssignedFields(t *testing.T) {
+	signed := []byte(`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "sample-svc", "namespace": "sample-ns"}, "spec": {"ports": [{"port": 80}], "selector": {"app": "sample"}}}`)
+	updated := []byte(`{"apiVersion": "v1", "kind": "Service", "metadata": {"name": "sample-svc", "namespace": "sample-ns"}, "spec": {"clusterIP": "10.96.12.34", "clusterIPs": ["10.96.12.34"], "ports": [{"port": 80}], "selector": {"app": "sample"}}}`)
+	var obj unstructured.Unstructured
+	_ = json.Unmarshal(updated, &obj.Object)
+
+	signedNode, _ := mapnode.NewFromBytes(signed)
+	updatedNode, _ := mapnode.NewFromBytes(updated)
+	dr := updatedNode.Diff(signedNode)
+
+	_, fields := RequestFilterProfile{}.GetServerAssignedFields().Match(obj)
+	_, unfiltered, _ := dr.Filter(fields)
+	if unfiltered.Size() != 0 {
+		t.Errorf("service with a server-assigned clusterIP should match its signed manifest; diff: %s", unfiltered.String())
+	}
+
+	// disabled by an empty list
+	profile := RequestFilterProfile{ServerAssignedFields: k8smanifest.ObjectFieldBindingList{}}
+	_, fields = profile.GetServerAssignedFields().Match(obj)
+	if len(fields) != 0 {
+		t.Errorf("server-assigned fields should not be ignored with an empty list: %v", fields)
+	}
+}
+
+func TestServerManagedFields(t *testing.T) {
+	signed := []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "sample-cm", "namespace": "sample-ns"}, "data": {"key1": "val1"}}`)
+	live := []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "sample-cm", "namespace": "sample-ns", "resourceVersion": "12345", "uid": "6b5a3a1e-5a4f-4c1b-9c1a-8f0c9d0a1b2c", "creationTimestamp": "2021-09-01T12:00:00Z",
+		"managedFields": [{"manager": "kubectl", "operation": "Apply", "apiVersion": "v1", "fieldsType": "FieldsV1"}]}, "data": {"key1": "val1"}}`)
+	var obj unstructured.Unstructured
+	_ = json.Unmarshal(live, &obj.Object)
+
+	signedNode, _ := mapnode.NewFromBytes(signed)
+	liveNode, _ := mapnode.NewFromBytes(live)
+	dr := liveNode.Diff(signedNode)
+
+	_, fields := RequestFilterProfile{}.GetServerManagedIgnoreFields().Match(obj)
+	_, unfiltered, _ := dr.Filter(fields)
+	if unfiltered.Size() != 0 {
+		t.Errorf("object differing only in server-managed fields should match its signed manifest; diff: %s", unfiltered.String())
+	}
+
+	// disabled by an empty list, but managedFields are still stripped
+	profile := RequestFilterProfile{ServerManagedFields: []string{}}
+	_, fields = profile.GetServerManagedIgnoreFields().Match(obj)
+	_, unfiltered, _ = dr.Filter(fields)
+	if len(unfiltered.Items) == 0 {
+		t.Error("server-managed fields should not be stripped with an empty list")
+	}
+	for _, item := range unfiltered.Items {
+		if strings.HasPrefix(item.Key, "metadata.managedFields") {
+			t.Errorf("managedFields should always be stripped; diff: %s", unfiltered.String())
+		}
+	}
+}
+
+func TestMergeRequestFilterProfile(t *testing.T) {
+	base := RequestFilterProfile{
+		SkipObjects:       k8smanifest.ObjectReferenceList{{Kind: "ConfigMap", Name: "kube-root-ca.crt"}},
+		SkipUsers:         ObjectUserBindingList{{Users: []string{"system:admin"}}},
+		StripMetadataKeys: []string{"pod-template-hash"},
+		ApiGroups:         ApiGroupFilter{Include: []string{"apps", "core"}},
+	}
+	teamA := RequestFilterProfile{
+		SkipObjects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap", Name: "kube-root-ca.crt"}, {Kind: "Secret", Name: "team-a-*"}},
+		IgnoreFields: k8smanifest.ObjectFieldBindingList{
+			{Fields: []string{"data.comment"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap"}}},
+		},
+		StripMetadataKeys: []string{"pod-template-hash", "argocd.argoproj.io/*"},
+	}
+	teamB := RequestFilterProfile{
+		SkipUsers: ObjectUserBindingList{{Users: []string{"system:admin"}}, {Groups: []string{"platform-admins"}}},
+		IgnoreFields: k8smanifest.ObjectFieldBindingList{
+			{Fields: []string{"data.comment"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap"}}},
+			{Fields: []string{"spec.replicas"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "Deployment"}}},
+		},
+		ApiGroups:        ApiGroupFilter{Include: []string{"*"}, Exclude: []string{"internal.example.com"}},
+		SkipOwnedObjects: true,
+	}
+
+	merged := base.Merge(teamA, teamB)
+	if len(merged.SkipObjects) != 2 || merged.SkipObjects[1].Kind != "Secret" {
+		t.Errorf("skip objects should be concatenated without duplicates: %v", merged.SkipObjects)
+	}
+	if len(merged.SkipUsers) != 2 {
+		t.Errorf("skip users should be concatenated without duplicates: %v", merged.SkipUsers)
+	}
+	if len(merged.IgnoreFields) != 2 {
+		t.Errorf("ignore fields should be concatenated without duplicates: %v", merged.IgnoreFields)
+	}
+	if len(merged.StripMetadataKeys) != 2 {
+		t.Errorf("strip metadata keys should be concatenated without duplicates: %v", merged.StripMetadataKeys)
+	}
+	if len(merged.ApiGroups.Include) != 2 || len(merged.ApiGroups.Exclude) != 1 {
+		t.Errorf("include should be taken from the base profile and exclude should be concatenated: %v", merged.ApiGroups)
+	}
+	if merged.SkipOwnedObjects {
+		t.Error("skipOwnedObjects should be taken from the base profile")
+	}
+	if len(base.SkipObjects) != 1 {
+		t.Error("base profile should not be modified")
+	}
+}
@@ -0,0 +1,115 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+// getCanonicallyEqualFields returns the fields of the diff whose values are equal except for their types,
+// e.g. `1` and `"1"`, or `true` and `"true"` written by different YAML libraries.
+// The order of map keys is not significant in the diff already.
+// The second return value is true only if all differences are such cosmetic ones.
+func getCanonicallyEqualFields(diff *mapnode.DiffResult) ([]string, bool) {
+	if diff == nil || diff.Size() == 0 {
+		return nil, false
+	}
+	fields := []string{}
+	all := true
+	for _, item := range diff.Items {
+		before, beforeFound := item.Values["before"]
+		after, afterFound := item.Values["after"]
+		if !beforeFound || !afterFound || before == nil || after == nil || !isCanonicallyEqual(before, after) {
+			all = false
+			continue
+		}
+		fields = append(fields, item.Key)
+	}
+	return fields, all
+}
+
+// isCanonicallyEqual compares two values. A number equals only the string of its literal, e.g. `1.5` and `"1.5"`,
+// and a bool equals only `"true"` or `"false"`. Two strings must be exactly the same, so "1.10" and "1.1",
+// or "01" and "1", are different.
+func isCanonicallyEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			bElement, found := bv[k]
+			if !found || !isCanonicallyEqual(v, bElement) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !isCanonicallyEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case string:
+		if bs, ok := b.(string); ok {
+			return av == bs
+		}
+		return isLiteralOf(av, b)
+	}
+	if bs, ok := b.(string); ok {
+		return isLiteralOf(bs, a)
+	}
+	if af, ok := toFloat(a); ok {
+		bf, ok := toFloat(b)
+		return ok && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// isLiteralOf returns true if the string is the literal of the number or the bool.
+func isLiteralOf(s string, v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return s == strconv.FormatBool(b)
+	}
+	if f, ok := toFloat(v); ok {
+		return s == strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return false
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch val := v.(type) {
+	case float64:
+		return val, true
+	case float32:
+		return float64(val), true
+	case int, int32, int64, uint, uint32, uint64:
+		f, err := strconv.ParseFloat(fmt.Sprint(val), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
@@ -0,0 +1,66 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+func TestGetCanonicallyEqualFields(t *testing.T) {
+	signed, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app"}, "spec": {"replicas": 1.0, "paused": "false", "template": {"metadata": {"labels": {"app": "sample-app", "version": "2"}}}}}`))
+	live, _ := mapnode.NewFromBytes([]byte(`{"spec": {"template": {"metadata": {"labels": {"version": 2, "app": "sample-app"}}}, "paused": false, "replicas": 1}, "metadata": {"name": "sample-app"}, "kind": "Deployment"}`))
+	if _, ok := getCanonicallyEqualFields(live.Diff(signed)); !ok {
+		t.Error("semantically-equal manifests should pass")
+	}
+
+	changed, _ := mapnode.NewFromBytes([]byte(`{"spec": {"template": {"metadata": {"labels": {"version": 2, "app": "sample-app"}}}, "paused": false, "replicas": 2}, "metadata": {"name": "sample-app"}, "kind": "Deployment"}`))
+	if _, ok := getCanonicallyEqualFields(changed.Diff(signed)); ok {
+		t.Error("manifests with a different value should fail")
+	}
+}
+
+func TestIsCanonicallyEqual(t *testing.T) {
+	equal := [][2]interface{}{
+		{float64(1), "1"},
+		{"1.5", float64(1.5)},
+		{int64(1), float64(1)},
+		{true, "true"},
+		{"false", false},
+		{map[string]interface{}{"port": int64(80)}, map[string]interface{}{"port": "80"}},
+	}
+	for _, pair := range equal {
+		if !isCanonicallyEqual(pair[0], pair[1]) {
+			t.Errorf("%#v and %#v should be equal", pair[0], pair[1])
+		}
+	}
+	different := [][2]interface{}{
+		{"1.10", "1.1"},
+		{"1000", "1e3"},
+		{"01", "1"},
+		{"True", "true"},
+		{"01", float64(1)},
+		{"1e3", float64(1000)},
+		{"yes", true},
+	}
+	for _, pair := range different {
+		if isCanonicallyEqual(pair[0], pair[1]) {
+			t.Errorf("%#v and %#v should be different", pair[0], pair[1])
+		}
+	}
+}
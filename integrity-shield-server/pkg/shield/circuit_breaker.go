@@ -0,0 +1,155 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"net"
+	"regexp"
+	"sync"
+	"syscall"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultCircuitBreakerCooldown = 30 * time.Second
+
+// states of the circuit breaker; the values are exposed as the metric
+const (
+	circuitClosed   = 0
+	circuitOpen     = 1
+	circuitHalfOpen = 2
+)
+
+var errVerificationCircuitOpen = errors.New("verification is skipped because the verification backend keeps failing (circuit breaker is open)")
+
+var circuitBreakerState = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "integrityshield_verify_circuit_breaker_state",
+	Help: "State of the circuit breaker around the verification backend. 0: closed, 1: open, 2: half-open.",
+})
+
+func init() {
+	prometheus.MustRegister(circuitBreakerState)
+}
+
+// verifyBreaker is shared by all requests
+var verifyBreaker = &circuitBreaker{now: time.Now}
+
+// circuitBreaker trips after consecutive failures of the verification backend, so that requests fail fast
+// instead of waiting for the timeout during an outage. After the cooldown, one request is let through as a probe;
+// its success closes the circuit, and its failure opens it again.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     int
+	openedAt  time.Time
+	now       func() time.Time
+}
+
+// configure updates the threshold and the cooldown. A threshold of 0 disables the circuit breaker.
+func (b *circuitBreaker) configure(config k8smnfconfig.CircuitBreakerConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.threshold = config.FailureThreshold
+	b.cooldown = config.Cooldown.Duration
+	if b.cooldown == 0 {
+		b.cooldown = defaultCircuitBreakerCooldown
+	}
+	if b.threshold == 0 {
+		b.setState(circuitClosed)
+		b.failures = 0
+	}
+}
+
+// allow returns false if the call to the verification backend should be skipped.
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		// probe
+		b.setState(circuitHalfOpen)
+		return true
+	case circuitHalfOpen:
+		// wait for the result of the probe
+		return false
+	}
+	return true
+}
+
+// record updates the state with the result of a call to the verification backend.
+// failed must be true only for failures of the backend, not for errors of the resource; see isBackendFailure.
+func (b *circuitBreaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold == 0 {
+		return
+	}
+	if !failed {
+		if b.state != circuitClosed {
+			log.Info("verification backend is recovered; circuit breaker is closed")
+		}
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		if b.state != circuitOpen {
+			log.Warningf("verification backend failed %d times in a row; circuit breaker is open for %s", b.failures, b.cooldown)
+		}
+		b.openedAt = b.now()
+		b.setState(circuitOpen)
+	}
+}
+
+func (b *circuitBreaker) setState(state int) {
+	b.state = state
+	circuitBreakerState.Set(float64(state))
+}
+
+// 5xx status in errors from the registry client ("unexpected status code 503") or the Rekor client ("[GET /api/v1/log][503]")
+var backendServerErrorPattern = regexp.MustCompile(`(?i)(status code:? ?|\]\[|status:? )5[0-9][0-9]\b`)
+
+// isBackendFailure returns true if the error is a failure of the verification backend; a timeout,
+// a transport error or a 5xx response of the registry or Rekor. Errors of the resource, e.g. a missing signature
+// or a malformed annotation, are not failures of the backend, so that unsigned requests cannot trip the breaker.
+func isBackendFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if isVerificationTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return true
+	}
+	return backendServerErrorPattern.MatchString(err.Error())
+}
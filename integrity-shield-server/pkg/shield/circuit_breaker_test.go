@@ -0,0 +1,114 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	b := &circuitBreaker{now: func() time.Time { return now }}
+	b.configure(k8smnfconfig.CircuitBreakerConfig{FailureThreshold: 3, Cooldown: metav1.Duration{Duration: time.Minute}})
+
+	// trip
+	for i := 0; i < 3; i++ {
+		if !b.allow() {
+			t.Errorf("circuit should be closed before %d failures", i+1)
+		}
+		b.record(true)
+	}
+	if b.allow() {
+		t.Error("circuit should be open after consecutive failures")
+	}
+	if state := testutil.ToFloat64(circuitBreakerState); state != circuitOpen {
+		t.Errorf("metric should report the open state: %v", state)
+	}
+
+	// cooldown
+	now = now.Add(30 * time.Second)
+	if b.allow() {
+		t.Error("circuit should stay open during the cooldown")
+	}
+	now = now.Add(time.Minute)
+	if !b.allow() {
+		t.Error("a probe should be allowed after the cooldown")
+	}
+	if b.allow() {
+		t.Error("only one probe should be allowed while half-open")
+	}
+	b.record(true)
+	if b.allow() {
+		t.Error("failed probe should open the circuit again")
+	}
+
+	// reset
+	now = now.Add(2 * time.Minute)
+	if !b.allow() {
+		t.Error("a probe should be allowed after the cooldown")
+	}
+	b.record(false)
+	if !b.allow() || b.failures != 0 {
+		t.Error("successful probe should close the circuit")
+	}
+	if state := testutil.ToFloat64(circuitBreakerState); state != circuitClosed {
+		t.Errorf("metric should report the closed state: %v", state)
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := &circuitBreaker{now: time.Now}
+	b.configure(k8smnfconfig.CircuitBreakerConfig{})
+	for i := 0; i < 10; i++ {
+		b.record(true)
+	}
+	if !b.allow() {
+		t.Error("disabled circuit breaker should never open")
+	}
+}
+
+func TestIsBackendFailure(t *testing.T) {
+	failures := []error{
+		&verificationTimeoutError{message: "verification timed out"},
+		errors.Wrap(&net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}, "failed to pull the manifest image"),
+		errors.New("GET https://registry.example.com/v2/: unexpected status code 503 Service Unavailable"),
+		errors.New("[GET /api/v1/log/entries/retrieve][502] searchLogQuery default"),
+	}
+	for _, err := range failures {
+		if !isBackendFailure(err) {
+			t.Errorf("`%s` should be a failure of the backend", err.Error())
+		}
+	}
+	resourceErrors := []error{
+		errors.New("failed to get signature: `cosign.sigstore.dev/message` is not found in the annotations"),
+		errors.New("failed to decode the signature annotation"),
+		errors.New("GET https://registry.example.com/v2/sample/manifests/latest: unexpected status code 404 Not Found"),
+	}
+	for _, err := range resourceErrors {
+		if isBackendFailure(err) {
+			t.Errorf("`%s` should not be a failure of the backend", err.Error())
+		}
+	}
+}
@@ -0,0 +1,66 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+var configReloadTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "integrityshield_config_reload_timestamp",
+	Help: "Unix time when the request handler config was loaded with a new content.",
+})
+
+var activeConfigHash string
+var activeConfigHashMutex sync.RWMutex
+
+func init() {
+	prometheus.MustRegister(configReloadTimestamp)
+}
+
+func getConfigHash(cfgBytes []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(cfgBytes))
+}
+
+// recordConfigLoad updates the hash of the active request handler config.
+// It returns true if the config is loaded for the first time or changed since the last load.
+func recordConfigLoad(hash string, now time.Time) bool {
+	activeConfigHashMutex.Lock()
+	defer activeConfigHashMutex.Unlock()
+	if hash == activeConfigHash {
+		return false
+	}
+	activeConfigHash = hash
+	configReloadTimestamp.Set(float64(now.Unix()))
+	log.WithFields(log.Fields{
+		"configHash": hash,
+	}).Info("request handler config is loaded")
+	return true
+}
+
+// GetActiveConfigHash returns the hash of the request handler config loaded last.
+func GetActiveConfigHash() string {
+	activeConfigHashMutex.RLock()
+	defer activeConfigHashMutex.RUnlock()
+	return activeConfigHash
+}
@@ -0,0 +1,44 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordConfigLoad(t *testing.T) {
+	now := time.Now()
+	hash1 := getConfigHash([]byte("log:\n  level: info\n"))
+	hash2 := getConfigHash([]byte("log:\n  level: debug\n"))
+	if hash1 == hash2 {
+		t.Errorf("hash should change when config changes: %s", hash1)
+	}
+
+	if !recordConfigLoad(hash1, now) {
+		t.Errorf("first load should be recorded")
+	}
+	if recordConfigLoad(hash1, now.Add(time.Second)) {
+		t.Errorf("loading the same config should not be recorded as a reload")
+	}
+	if !recordConfigLoad(hash2, now.Add(2*time.Second)) {
+		t.Errorf("changed config should be recorded as a reload")
+	}
+	if GetActiveConfigHash() != hash2 {
+		t.Errorf("active config hash should be the latest one; %s", GetActiveConfigHash())
+	}
+}
@@ -0,0 +1,64 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/json"
+	"time"
+
+	v1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// annotations which carry a signature of a resource
+var signatureAnnotationKeys = []string{
+	ImageRefAnnotationKeyShield,
+	AnnotationKeyDomain + "/message",
+	"cosign.sigstore.dev/signature",
+	"cosign.sigstore.dev/message",
+	"cosign.sigstore.dev/imageRef",
+}
+
+// isCreatedBeforeCutoff returns true if the request updates a resource created before the cutoff
+// and the update does not add or change a signature of the resource. The cutoff is moved earlier by the clock skew
+// allowance, so that the skew never exempts a resource created after the cutoff from the verification.
+func isCreatedBeforeCutoff(req admission.Request, resource unstructured.Unstructured, cutoff time.Time, skew time.Duration) bool {
+	if req.Operation != v1.Update {
+		return false
+	}
+	created := resource.GetCreationTimestamp()
+	if created.IsZero() || !created.Time.Before(cutoff.Add(-skew)) {
+		return false
+	}
+	var oldResource unstructured.Unstructured
+	if err := json.Unmarshal(req.OldObject.Raw, &oldResource); err != nil {
+		return false
+	}
+	return !signatureChanged(oldResource, resource)
+}
+
+func signatureChanged(oldResource, resource unstructured.Unstructured) bool {
+	oldAnnotations := oldResource.GetAnnotations()
+	annotations := resource.GetAnnotations()
+	for _, key := range signatureAnnotationKeys {
+		if annotations[key] != "" && annotations[key] != oldAnnotations[key] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	admv1 "k8s.io/api/admission/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestIsCreatedBeforeCutoff(t *testing.T) {
+	cutoff := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	obj := loadTestObject(t, testSSAConfigMap)
+	oldBytes, _ := json.Marshal(obj.Object)
+	req := admission.Request{
+		AdmissionRequest: admv1.AdmissionRequest{
+			Operation: admv1.Update,
+			OldObject: runtime.RawExtension{Raw: oldBytes},
+		},
+	}
+
+	// pre-cutoff object is grandfathered
+	obj.SetCreationTimestamp(metav1.NewTime(cutoff.Add(-24 * time.Hour)))
+	if !isCreatedBeforeCutoff(req, obj, cutoff, 0) {
+		t.Error("update of an object created before the cutoff should be audited")
+	}
+
+	// unless the update adds a signature
+	signed := obj.DeepCopy()
+	signed.SetAnnotations(map[string]string{"cosign.sigstore.dev/message": "H4sIAAAA..."})
+	if isCreatedBeforeCutoff(req, *signed, cutoff, 0) {
+		t.Error("update which adds a signature should be verified")
+	}
+
+	// post-cutoff object is verified
+	obj.SetCreationTimestamp(metav1.NewTime(cutoff.Add(time.Hour)))
+	if isCreatedBeforeCutoff(req, obj, cutoff, 0) {
+		t.Error("update of an object created after the cutoff should be verified")
+	}
+
+	// the clock skew allowance never exempts an object created after the cutoff
+	obj.SetCreationTimestamp(metav1.NewTime(cutoff.Add(time.Minute)))
+	if isCreatedBeforeCutoff(req, obj, cutoff, 5*time.Minute) {
+		t.Error("update of an object created after the cutoff should be verified regardless of the clock skew allowance")
+	}
+	// and an object created within the allowance before the cutoff is verified too
+	obj.SetCreationTimestamp(metav1.NewTime(cutoff.Add(-time.Minute)))
+	if isCreatedBeforeCutoff(req, obj, cutoff, 5*time.Minute) {
+		t.Error("update of an object created within the clock skew allowance before the cutoff should be verified")
+	}
+	obj.SetCreationTimestamp(metav1.NewTime(cutoff.Add(-time.Hour)))
+	if !isCreatedBeforeCutoff(req, obj, cutoff, 5*time.Minute) {
+		t.Error("update of an object created before the cutoff and the allowance should be audited")
+	}
+
+	// create requests are always verified
+	req.Operation = admv1.Create
+	obj.SetCreationTimestamp(metav1.Time{})
+	if isCreatedBeforeCutoff(req, obj, cutoff, 0) {
+		t.Error("create request should be verified")
+	}
+}
@@ -0,0 +1,202 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	k8smnfutil "github.com/sigstore/k8s-manifest-sigstore/pkg/util"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+	"github.com/sigstore/sigstore/pkg/signature"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The digest of the canonical manifest and its signature created by `cosign sign-blob` with the digest string.
+// With these annotations, a resource is verified by comparing the digests without pulling any reference manifest.
+const (
+	ManifestDigestAnnotationKey          = "integrityshield.io/manifestDigest"
+	ManifestDigestSignatureAnnotationKey = "integrityshield.io/manifestDigestSignature"
+)
+
+// canonicalManifestMask is the fixed set of fields removed from canonical manifests; the fields set by the API server
+// and status. It does not depend on the config, so that a signer can compute the same digest offline.
+var canonicalManifestMask = []string{
+	"metadata.creationTimestamp",
+	"metadata.uid",
+	"metadata.generation",
+	"metadata.managedFields",
+	"metadata.selfLink",
+	"metadata.resourceVersion",
+	"status",
+}
+
+func hasManifestDigest(resource unstructured.Unstructured) bool {
+	_, found := resource.GetAnnotations()[ManifestDigestAnnotationKey]
+	return found
+}
+
+// getCanonicalDigest returns the digest "sha256:<hex>" of the resource without the digest annotations and
+// the fields in canonicalManifestMask. Keys of the canonical JSON are sorted, so the digest does not depend
+// on the order of fields.
+func getCanonicalDigest(resource unstructured.Unstructured) (string, error) {
+	obj := resource.DeepCopy()
+	annotations := obj.GetAnnotations()
+	delete(annotations, ManifestDigestAnnotationKey)
+	delete(annotations, ManifestDigestSignatureAnnotationKey)
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+	} else {
+		obj.SetAnnotations(annotations)
+	}
+	objBytes, err := json.Marshal(obj.Object)
+	if err != nil {
+		return "", err
+	}
+	node, err := mapnode.NewFromBytes(objBytes)
+	if err != nil {
+		return "", err
+	}
+	canonical, err := json.Marshal(node.Mask(canonicalManifestMask).ToMap())
+	if err != nil {
+		return "", err
+	}
+	digest := sha256.Sum256(canonical)
+	return "sha256:" + hex.EncodeToString(digest[:]), nil
+}
+
+// verifyResourceWithDigest verifies the signature of the digest annotation with the keys of the verify option,
+// checks the signer with the signers of the option, and then compares the digest with the canonical digest
+// of the resource. Ignore fields of the option are not applied; see canonicalManifestMask.
+// The annotation has only one signature, so it cannot satisfy requiredSignatures more than 1.
+func verifyResourceWithDigest(resource unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption, requiredSignatures int) (*k8smanifest.VerifyResourceResult, error) {
+	if requiredSignatures > 1 {
+		return nil, errors.New(fmt.Sprintf("manifest digest has only one signature, but %d signatures are required", requiredSignatures))
+	}
+	annotations := resource.GetAnnotations()
+	expected := annotations[ManifestDigestAnnotationKey]
+	digestSignature := annotations[ManifestDigestSignatureAnnotationKey]
+	if digestSignature == "" {
+		return nil, errors.New(fmt.Sprintf("annotation `%s` is required to verify the manifest digest", ManifestDigestSignatureAnnotationKey))
+	}
+	signer := ""
+	unmatchedSigners := []string{}
+	for _, keyPath := range strings.Split(vo.KeyPath, ",") {
+		if keyPath == "" {
+			continue
+		}
+		identity, err := verifyBlobSignature([]byte(expected), digestSignature, keyPath)
+		if err != nil {
+			continue
+		}
+		if !matchSigners(identity, vo.Signers) {
+			unmatchedSigners = append(unmatchedSigners, identity)
+			continue
+		}
+		signer = identity
+		break
+	}
+	if signer == "" && len(unmatchedSigners) > 0 {
+		return nil, errors.New(fmt.Sprintf("the manifest digest is signed by %s, but the signer is not allowed", strings.Join(unmatchedSigners, ",")))
+	}
+	if signer == "" {
+		return nil, errors.New("the signature of the manifest digest is not verified with any keys")
+	}
+	actual, err := getCanonicalDigest(resource)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the digest of the resource")
+	}
+	if actual != expected {
+		return nil, errors.New(fmt.Sprintf("manifest digest does not match; signed: %s, actual: %s", expected, actual))
+	}
+	return &k8smanifest.VerifyResourceResult{
+		Verified: true,
+		InScope:  true,
+		Signer:   signer,
+	}, nil
+}
+
+// matchSigners returns true if the signer matches any of the signers, or no signers are configured,
+// as VerifyResource does.
+func matchSigners(signer string, signers []string) bool {
+	if len(signers) == 0 {
+		return true
+	}
+	return k8smnfutil.MatchWithPatternArray(signer, signers)
+}
+
+// verifyBlobSignature verifies a base64 encoded signature created by `cosign sign-blob` with the verifier of sigstore.
+// It returns the identity of the signer; the email or the common name of a certificate,
+// or the fingerprint "sha256:<hex>" of a public key.
+func verifyBlobSignature(data []byte, sigStr, keyPath string) (string, error) {
+	keyPEM, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return "", err
+	}
+	block, _ := pem.Decode(keyPEM)
+	if block == nil {
+		return "", errors.New(fmt.Sprintf("failed to decode the public key `%s` as PEM", keyPath))
+	}
+	pub, identity, err := parseVerificationKey(block)
+	if err != nil {
+		return "", err
+	}
+	verifier, err := signature.LoadVerifier(pub, crypto.SHA256)
+	if err != nil {
+		return "", errors.Wrap(err, fmt.Sprintf("failed to load the public key `%s`", keyPath))
+	}
+	sig, err := base64.StdEncoding.DecodeString(sigStr)
+	if err != nil {
+		return "", err
+	}
+	if err := verifier.VerifySignature(bytes.NewReader(sig), bytes.NewReader(data)); err != nil {
+		return "", errors.Wrap(err, "invalid signature")
+	}
+	return identity, nil
+}
+
+// parseVerificationKey returns the public key and the identity of a PEM block of a certificate or a public key.
+func parseVerificationKey(block *pem.Block) (interface{}, string, error) {
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, "", err
+		}
+		identity := cert.Subject.CommonName
+		if len(cert.EmailAddresses) > 0 {
+			identity = cert.EmailAddresses[0]
+		}
+		return cert.PublicKey, identity, nil
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, "", err
+	}
+	fingerprint := sha256.Sum256(block.Bytes)
+	return pub, "sha256:" + hex.EncodeToString(fingerprint[:]), nil
+}
@@ -0,0 +1,161 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestVerifyResourceWithDigest(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pubBytes, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	_ = ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}), 0644)
+	vo := &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = keyPath
+	// ignore fields in the config do not change the digest which the signer computes offline
+	vo.IgnoreFields = k8smanifest.ObjectFieldBindingList{
+		{Fields: []string{"data.comment"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap"}}},
+	}
+
+	// sign the digest of the manifest
+	signed := loadTestObject(t, `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "sample-cm", "namespace": "sample-ns"}, "data": {"key1": "val1"}}`)
+	digest, err := getCanonicalDigest(signed)
+	if err != nil {
+		t.Fatalf("failed to get digest: %s", err.Error())
+	}
+	hash := sha256.Sum256([]byte(digest))
+	sig, _ := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	annotate := func(obj unstructured.Unstructured) unstructured.Unstructured {
+		obj.SetAnnotations(map[string]string{
+			ManifestDigestAnnotationKey:          digest,
+			ManifestDigestSignatureAnnotationKey: base64.StdEncoding.EncodeToString(sig),
+		})
+		return obj
+	}
+
+	// admitted object with fields set by the API server
+	obj := annotate(*signed.DeepCopy())
+	obj.SetUID("6b5a3a1e-5a4f-4c1b-9c1a-8f0c9d0a1b2c")
+	obj.SetResourceVersion("12345")
+	result, err := verifyResourceWithDigest(obj, vo, 1)
+	fingerprint := sha256.Sum256(pubBytes)
+	if err != nil || !result.Verified {
+		t.Errorf("matching digest should be verified; err: %v", err)
+	} else if result.Signer != "sha256:"+hex.EncodeToString(fingerprint[:]) {
+		t.Errorf("signer should be the fingerprint of the key, not the key path: %s", result.Signer)
+	}
+
+	// the digest covers the fields which are ignored in the config
+	obj = annotate(*signed.DeepCopy())
+	_ = unstructured.SetNestedField(obj.Object, "changed", "data", "comment")
+	if _, err := verifyResourceWithDigest(obj, vo, 1); err == nil {
+		t.Error("digest should not be verified if a field out of the fixed mask is changed")
+	}
+
+	// the signer is checked with the signers in the option
+	obj = annotate(*signed.DeepCopy())
+	signersVo := *vo
+	signersVo.Signers = []string{"sha256:" + hex.EncodeToString(fingerprint[:])}
+	if result, err := verifyResourceWithDigest(obj, &signersVo, 1); err != nil || !result.Verified {
+		t.Errorf("digest signed by an allowed signer should be verified; err: %v", err)
+	}
+	signersVo.Signers = []string{"signer@example.com"}
+	if _, err := verifyResourceWithDigest(obj, &signersVo, 1); err == nil {
+		t.Error("digest signed by a signer who is not allowed should not be verified")
+	}
+
+	// a single signature over the digest cannot satisfy N-of-M
+	if _, err := verifyResourceWithDigest(obj, vo, 2); err == nil {
+		t.Error("digest verification should be rejected when more than 1 signature is required")
+	}
+
+	// mismatched digest
+	obj = annotate(*signed.DeepCopy())
+	_ = unstructured.SetNestedField(obj.Object, "changed", "data", "key1")
+	if _, err := verifyResourceWithDigest(obj, vo, 1); err == nil {
+		t.Error("mismatched digest should not be verified")
+	}
+
+	// digest signed by another key
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	otherSig, _ := ecdsa.SignASN1(rand.Reader, otherKey, hash[:])
+	obj = annotate(*signed.DeepCopy())
+	annotations := obj.GetAnnotations()
+	annotations[ManifestDigestSignatureAnnotationKey] = base64.StdEncoding.EncodeToString(otherSig)
+	obj.SetAnnotations(annotations)
+	if _, err := verifyResourceWithDigest(obj, vo, 1); err == nil {
+		t.Error("digest signed by an unknown key should not be verified")
+	}
+}
+
+func TestVerifySignedConstraintTemplate(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pubBytes, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	keyPath := filepath.Join(t.TempDir(), "cosign.pub")
+	_ = ioutil.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}), 0644)
+	vo := &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = keyPath
+
+	// ConstraintTemplate has a schema embedded in a CRD spec and rego in a list of targets
+	signed := loadTestObject(t, `{"apiVersion": "templates.gatekeeper.sh/v1beta1", "kind": "ConstraintTemplate", "metadata": {"name": "k8srequiredlabels"},
+		"spec": {"crd": {"spec": {"names": {"kind": "K8sRequiredLabels"}, "validation": {"openAPIV3Schema": {"properties": {"labels": {"type": "array", "items": {"type": "string"}}}}}}},
+		"targets": [{"target": "admission.k8s.gatekeeper.sh", "rego": "package k8srequiredlabels\n\nviolation[{\"msg\": msg}] {\n  provided := {label | input.review.object.metadata.labels[label]}\n  required := {label | label := input.parameters.labels[_]}\n  missing := required - provided\n  count(missing) > 0\n  msg := sprintf(\"missing labels: %v\", [missing])\n}\n"}]}}`)
+	digest, err := getCanonicalDigest(signed)
+	if err != nil {
+		t.Fatalf("failed to get digest: %s", err.Error())
+	}
+	hash := sha256.Sum256([]byte(digest))
+	sig, _ := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	annotate := func(obj unstructured.Unstructured) unstructured.Unstructured {
+		obj.SetAnnotations(map[string]string{
+			ManifestDigestAnnotationKey:          digest,
+			ManifestDigestSignatureAnnotationKey: base64.StdEncoding.EncodeToString(sig),
+		})
+		return obj
+	}
+
+	// status written by gatekeeper does not affect the verification
+	obj := annotate(*signed.DeepCopy())
+	_ = unstructured.SetNestedField(obj.Object, true, "status", "created")
+	result, err := verifyResourceWithDigest(obj, vo, 1)
+	if err != nil || !result.Verified {
+		t.Errorf("signed ConstraintTemplate should be verified; err: %v", err)
+	}
+
+	// modified rego
+	obj = annotate(*signed.DeepCopy())
+	targets, _, _ := unstructured.NestedSlice(obj.Object, "spec", "targets")
+	targets[0].(map[string]interface{})["rego"] = "package k8srequiredlabels\n"
+	_ = unstructured.SetNestedSlice(obj.Object, targets, "spec", "targets")
+	if _, err := verifyResourceWithDigest(obj, vo, 1); err == nil {
+		t.Error("ConstraintTemplate with modified rego should not be verified")
+	}
+}
@@ -0,0 +1,125 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/kubeutil"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// the state of the toggle is reused for this period, so that IntegrityShield CR is not listed on every denial
+const enforcementToggleCacheTTL = 10 * time.Second
+
+var integrityShieldGVR = schema.GroupVersionResource{
+	Group:    "apis.integrityshield.io",
+	Version:  "v1alpha1",
+	Resource: "integrityshields",
+}
+
+// newToggleClient builds a client for loading IntegrityShield CR. It can be replaced in tests.
+var newToggleClient = func() (dynamic.Interface, error) {
+	config, err := kubeutil.GetKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(config)
+}
+
+// the cached state of the toggle and the time when it is loaded
+var enforcementDisabled bool
+var enforcementToggleLoadedAt time.Time
+var enforcementToggleMutex sync.Mutex
+
+// applyEnforcementToggle allows a denied request if `spec.enforcementDisabled` is set in IntegrityShield CR.
+// This is applied in all modes, i.e. both for Gatekeeper and for the admission controller.
+func applyEnforcementToggle(r *ResultFromRequestHandler) {
+	if r.Allow || !checkEnforcementToggle(time.Now()) {
+		return
+	}
+	r.Allow = true
+	r.Message = "allowed because enforcement is disabled: " + r.Message
+}
+
+// checkEnforcementToggle returns true if `spec.enforcementDisabled` is set in IntegrityShield CR.
+// The CR is loaded again when the cached state is older than the TTL, so flipping the toggle
+// changes the response without restart.
+func checkEnforcementToggle(now time.Time) bool {
+	enforcementToggleMutex.Lock()
+	if !enforcementToggleLoadedAt.IsZero() && now.Sub(enforcementToggleLoadedAt) < enforcementToggleCacheTTL {
+		disabled := enforcementDisabled
+		enforcementToggleMutex.Unlock()
+		return disabled
+	}
+	enforcementToggleMutex.Unlock()
+
+	namespace := os.Getenv("POD_NAMESPACE")
+	if namespace == "" {
+		namespace = defaultPodNamespace
+	}
+	client, err := newToggleClient()
+	if err != nil {
+		log.Errorf("failed to create a client for IntegrityShield CR; %s", err.Error())
+		return false
+	}
+	disabled, crName := isEnforcementDisabled(client, namespace)
+
+	enforcementToggleMutex.Lock()
+	defer enforcementToggleMutex.Unlock()
+	if disabled != enforcementDisabled {
+		if disabled {
+			log.WithFields(log.Fields{
+				"integrityShield": crName,
+				"namespace":       namespace,
+			}).Warning("ENFORCEMENT DISABLED: requests are audited but not denied until the toggle is turned off")
+		} else {
+			log.WithFields(log.Fields{
+				"namespace": namespace,
+			}).Warning("ENFORCEMENT ENABLED: requests are denied again")
+		}
+	}
+	enforcementDisabled = disabled
+	enforcementToggleLoadedAt = now
+	return disabled
+}
+
+// isEnforcementDisabled returns true and the name of the CR if any IntegrityShield CR in the namespace disables enforcement.
+func isEnforcementDisabled(client dynamic.Interface, namespace string) (bool, string) {
+	list, err := client.Resource(integrityShieldGVR).Namespace(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Warning("failed to list IntegrityShield CR; ", err.Error())
+		return false, ""
+	}
+	for _, item := range list.Items {
+		disabled, found, err := unstructured.NestedBool(item.Object, "spec", "enforcementDisabled")
+		if err != nil || !found {
+			continue
+		}
+		if disabled {
+			return true, item.GetName()
+		}
+	}
+	return false, ""
+}
@@ -0,0 +1,84 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestCheckEnforcementToggle(t *testing.T) {
+	cr := &unstructured.Unstructured{}
+	cr.SetAPIVersion("apis.integrityshield.io/v1alpha1")
+	cr.SetKind("IntegrityShield")
+	cr.SetNamespace(defaultPodNamespace)
+	cr.SetName("integrity-shield")
+
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{integrityShieldGVR: "IntegrityShieldList"}, cr)
+	orgFunc := newToggleClient
+	defer func() { newToggleClient = orgFunc }()
+	newToggleClient = func() (dynamic.Interface, error) {
+		return client, nil
+	}
+	enforcementToggleLoadedAt = time.Time{}
+
+	now := time.Now()
+	if checkEnforcementToggle(now) {
+		t.Error("enforcement should be enabled by default")
+	}
+
+	// flip the toggle without restart
+	_ = unstructured.SetNestedField(cr.Object, true, "spec", "enforcementDisabled")
+	_, err := client.Resource(integrityShieldGVR).Namespace(defaultPodNamespace).Update(context.Background(), cr, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatalf("failed to update IntegrityShield CR: %s", err.Error())
+	}
+	// the cached state is used within the TTL
+	actions := len(client.Actions())
+	if checkEnforcementToggle(now.Add(time.Second)) {
+		t.Error("the cached state should be used within the TTL")
+	}
+	if len(client.Actions()) != actions {
+		t.Error("IntegrityShield CR should not be listed within the TTL")
+	}
+	now = now.Add(enforcementToggleCacheTTL)
+	if !checkEnforcementToggle(now) {
+		t.Error("enforcement should be disabled after flipping the toggle")
+	}
+
+	// a denied request is allowed while enforcement is disabled
+	r := &ResultFromRequestHandler{Allow: false, Message: "no signature found"}
+	applyEnforcementToggle(r)
+	if !r.Allow {
+		t.Error("denied request should be allowed while enforcement is disabled")
+	}
+
+	_ = unstructured.SetNestedField(cr.Object, false, "spec", "enforcementDisabled")
+	_, _ = client.Resource(integrityShieldGVR).Namespace(defaultPodNamespace).Update(context.Background(), cr, metav1.UpdateOptions{})
+	if checkEnforcementToggle(now.Add(enforcementToggleCacheTTL)) {
+		t.Error("enforcement should be enabled again")
+	}
+}
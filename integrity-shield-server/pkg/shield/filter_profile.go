@@ -0,0 +1,64 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"sort"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/ghodss/yaml"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+)
+
+// configmaps with this label have fragments of RequestFilterProfile which are merged into the request handler config
+const RequestFilterProfileLabelSelector = "integrityshield.io/requestFilterProfile=true"
+
+// loadRequestFilterProfileFragments returns the RequestFilterProfile in each value of the labeled configmaps,
+// in the order of configmap names and keys. It also returns the raw content to be included in the config hash.
+// Invalid fragments are skipped so that one team's mistake does not break the whole config.
+func loadRequestFilterProfileFragments(clientset kubeclient.Interface, namespace string) ([]k8smnfconfig.RequestFilterProfile, []byte) {
+	cmList, err := clientset.CoreV1().ConfigMaps(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: RequestFilterProfileLabelSelector})
+	if err != nil {
+		log.Warning("failed to list request filter profile configmaps; ", err.Error())
+		return nil, nil
+	}
+	cms := cmList.Items
+	sort.Slice(cms, func(i, j int) bool { return cms[i].Name < cms[j].Name })
+
+	fragments := []k8smnfconfig.RequestFilterProfile{}
+	raw := []byte{}
+	for _, cm := range cms {
+		keys := []string{}
+		for key := range cm.Data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			var fragment k8smnfconfig.RequestFilterProfile
+			if err := yaml.Unmarshal([]byte(cm.Data[key]), &fragment); err != nil {
+				log.Errorf("failed to unmarshal request filter profile `%s` in configmap `%s`; %s", key, cm.Name, err.Error())
+				continue
+			}
+			fragments = append(fragments, fragment)
+			raw = append(raw, []byte(cm.Data[key])...)
+		}
+	}
+	return fragments, raw
+}
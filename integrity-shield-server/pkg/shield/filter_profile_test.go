@@ -0,0 +1,56 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestLoadRequestFilterProfileFragments(t *testing.T) {
+	labels := map[string]string{"integrityshield.io/requestFilterProfile": "true"}
+	teamB := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-b", Namespace: "integrity-shield-operator-system", Labels: labels},
+		Data: map[string]string{
+			"profile.yaml": "skipObjects:\n- kind: ConfigMap\n  name: team-b-*\n",
+			"invalid.yaml": "skipObjects: not-a-list\n",
+		},
+	}
+	teamA := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "team-a", Namespace: "integrity-shield-operator-system", Labels: labels},
+		Data: map[string]string{
+			"profile.yaml": "skipObjects:\n- kind: ConfigMap\n  name: team-a-*\nignoreFields:\n- fields:\n  - data.comment\n  objects:\n  - kind: ConfigMap\n",
+		},
+	}
+	unlabeled := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "integrity-shield-operator-system"},
+		Data:       map[string]string{"profile.yaml": "skipObjects:\n- kind: Secret\n"},
+	}
+	clientset := fake.NewSimpleClientset(teamB, teamA, unlabeled)
+
+	fragments, raw := loadRequestFilterProfileFragments(clientset, "integrity-shield-operator-system")
+	if len(fragments) != 2 || len(raw) == 0 {
+		t.Errorf("valid fragments in labeled configmaps should be loaded: %v", fragments)
+		return
+	}
+	if fragments[0].SkipObjects[0].Name != "team-a-*" || fragments[1].SkipObjects[0].Name != "team-b-*" {
+		t.Errorf("fragments should be ordered by configmap name: %v", fragments)
+	}
+}
@@ -0,0 +1,42 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// API groups of ConstraintTemplates and the constraints of the kinds defined by them
+var gatekeeperAPIGroups = []string{"templates.gatekeeper.sh", "constraints.gatekeeper.sh"}
+
+// GetGatekeeperIgnoreField returns an ignore field binding of the status of a ConstraintTemplate or a constraint.
+// Gatekeeper writes the status of each of its pods into these objects, so the status is never part of signed manifests.
+func GetGatekeeperIgnoreField(resource unstructured.Unstructured) (k8smanifest.ObjectFieldBinding, bool) {
+	group := resource.GroupVersionKind().Group
+	for _, g := range gatekeeperAPIGroups {
+		if group == g {
+			return k8smanifest.ObjectFieldBinding{
+				Fields: []string{"status", "status.*"},
+				Objects: k8smanifest.ObjectReferenceList{
+					{Kind: resource.GetKind(), Name: resource.GetName()},
+				},
+			}, true
+		}
+	}
+	return k8smanifest.ObjectFieldBinding{}, false
+}
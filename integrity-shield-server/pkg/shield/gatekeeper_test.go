@@ -0,0 +1,86 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	admv1 "k8s.io/api/admission/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const testConstraintTemplate = `{"apiVersion": "templates.gatekeeper.sh/v1beta1", "kind": "ConstraintTemplate", "metadata": {"name": "k8srequiredlabels"},
+	"spec": {"crd": {"spec": {"names": {"kind": "K8sRequiredLabels"}, "validation": {"openAPIV3Schema": {"properties": {"labels": {"type": "array", "items": {"type": "string"}}}}}}},
+	"targets": [{"target": "admission.k8s.gatekeeper.sh", "rego": "package k8srequiredlabels\n\nviolation[{\"msg\": msg}] {\n  count(input.review.object.metadata.labels) == 0\n  msg := \"no labels\"\n}\n"}]}}`
+
+func TestSignedConstraintTemplateIgnoreFields(t *testing.T) {
+	signed := loadTestObject(t, testConstraintTemplate)
+	req := admission.Request{AdmissionRequest: admv1.AdmissionRequest{Name: signed.GetName(), Operation: admv1.Update}}
+	newVerifyOption := func(obj unstructured.Unstructured) *k8smanifest.VerifyResourceOption {
+		vo := setVerifyOption(&k8smnfconfig.ParameterObject{}, &k8smnfconfig.RequestHandlerConfig{}, "", "")
+		appendRequestIgnoreFields(vo, req, obj, &k8smnfconfig.RequestHandlerConfig{})
+		return vo
+	}
+
+	// status written by Gatekeeper and metadata written by the API server do not affect the verification
+	obj := *signed.DeepCopy()
+	obj.SetResourceVersion("12345")
+	obj.SetUID("6b5a3a1e-5a4f-4c1b-9c1a-8f0c9d0a1b2c")
+	_ = unstructured.SetNestedField(obj.Object, true, "status", "created")
+	_ = unstructured.SetNestedSlice(obj.Object, []interface{}{map[string]interface{}{"id": "gatekeeper-audit", "observedGeneration": int64(1)}}, "status", "byPod")
+	if diff := getUnignoredDiff(t, obj, signed, newVerifyOption(obj)); diff != nil && diff.Size() > 0 {
+		t.Errorf("signed ConstraintTemplate should be verified; diff: %s", diff.String())
+	}
+
+	// modified schema in the embedded CRD spec
+	obj = *signed.DeepCopy()
+	_ = unstructured.SetNestedField(obj.Object, "integer", "spec", "crd", "spec", "validation", "openAPIV3Schema", "properties", "labels", "items", "type")
+	if diff := getUnignoredDiff(t, obj, signed, newVerifyOption(obj)); diff == nil || diff.Size() == 0 {
+		t.Error("ConstraintTemplate with a modified schema should not be verified")
+	}
+
+	// modified rego
+	obj = *signed.DeepCopy()
+	targets, _, _ := unstructured.NestedSlice(obj.Object, "spec", "targets")
+	targets[0].(map[string]interface{})["rego"] = "package k8srequiredlabels\n"
+	_ = unstructured.SetNestedSlice(obj.Object, targets, "spec", "targets")
+	if diff := getUnignoredDiff(t, obj, signed, newVerifyOption(obj)); diff == nil || diff.Size() == 0 {
+		t.Error("ConstraintTemplate with modified rego should not be verified")
+	}
+}
+
+func TestGetGatekeeperIgnoreField(t *testing.T) {
+	template := loadTestObject(t, testConstraintTemplate)
+	constraint := loadTestObject(t, `{"apiVersion": "constraints.gatekeeper.sh/v1beta1", "kind": "K8sRequiredLabels", "metadata": {"name": "ns-must-have-owner"}, "spec": {"parameters": {"labels": ["owner"]}}}`)
+	for _, obj := range []unstructured.Unstructured{template, constraint} {
+		binding, ok := GetGatekeeperIgnoreField(obj)
+		if !ok {
+			t.Errorf("status of %s should be ignored", obj.GetKind())
+			continue
+		}
+		if matched, fields := (k8smanifest.ObjectFieldBindingList{binding}).Match(obj); !matched || len(fields) == 0 {
+			t.Errorf("ignore field of %s should match the object", obj.GetKind())
+		}
+	}
+	cm := loadTestObject(t, `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "sample-cm", "namespace": "sample-ns"}}`)
+	if _, ok := GetGatekeeperIgnoreField(cm); ok {
+		t.Error("status of other objects should not be ignored")
+	}
+}
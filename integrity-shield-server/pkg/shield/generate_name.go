@@ -0,0 +1,41 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"strings"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// getGenerateNameIgnoreField returns an ignore field binding of `metadata.name` for a resource created with generateName.
+// A signed manifest of such a resource has only generateName, so the name generated by the API server is not compared.
+// If the name is not generated yet, nothing is ignored and the signed manifest is found by GVK and content, not by name.
+func getGenerateNameIgnoreField(resource unstructured.Unstructured) (k8smanifest.ObjectFieldBinding, bool) {
+	generateName := resource.GetGenerateName()
+	name := resource.GetName()
+	if generateName == "" || name == "" || !strings.HasPrefix(name, generateName) {
+		return k8smanifest.ObjectFieldBinding{}, false
+	}
+	return k8smanifest.ObjectFieldBinding{
+		Fields: []string{"metadata.name"},
+		Objects: k8smanifest.ObjectReferenceList{
+			{Kind: resource.GetKind(), Name: name},
+		},
+	}, true
+}
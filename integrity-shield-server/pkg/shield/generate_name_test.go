@@ -0,0 +1,65 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+const testGenerateNameManifest = `{
+	"apiVersion": "batch/v1",
+	"kind": "Job",
+	"metadata": {
+		"generateName": "sample-job-",
+		"namespace": "sample-ns"
+	},
+	"spec": {
+		"template": {"spec": {"containers": [{"name": "job", "image": "busybox"}], "restartPolicy": "Never"}}
+	}
+}`
+
+func TestGetGenerateNameIgnoreField(t *testing.T) {
+	obj := loadTestObject(t, testGenerateNameManifest)
+
+	// name is not generated yet
+	if _, ok := getGenerateNameIgnoreField(obj); ok {
+		t.Error("nothing should be ignored before the name is generated")
+	}
+
+	obj.SetName("sample-job-x7k2p")
+	binding, ok := getGenerateNameIgnoreField(obj)
+	if !ok {
+		t.Error("generated name should be ignored")
+		return
+	}
+	signed, _ := mapnode.NewFromBytes([]byte(testGenerateNameManifest))
+	objBytes, _ := json.Marshal(obj.Object)
+	objNode, _ := mapnode.NewFromBytes(objBytes)
+	_, unfiltered, _ := objNode.Diff(signed).Filter(binding.Fields)
+	if unfiltered.Size() != 0 {
+		t.Errorf("object with a generated name should match its signed manifest; diff: %s", unfiltered.String())
+	}
+
+	// name which is not generated from generateName
+	obj.SetName("another-job")
+	if _, ok := getGenerateNameIgnoreField(obj); ok {
+		t.Error("name not prefixed by generateName should be compared")
+	}
+}
@@ -0,0 +1,64 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+// ignoreFieldMatches counts how often each ignore field suppressed a difference,
+// to find the rules which never match and the rules which match too often.
+var ignoreFieldMatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "integrityshield_ignore_field_matches_total",
+	Help: "Number of times an ignore field suppressed a difference between old and new objects.",
+}, []string{"field"})
+
+func init() {
+	prometheus.MustRegister(ignoreFieldMatches)
+}
+
+// recordIgnoreFieldMatches increments the counter of each ignore field which matches a difference.
+func recordIgnoreFieldMatches(dr *mapnode.DiffResult, ignoreFields []string) {
+	if dr == nil || dr.Size() == 0 {
+		return
+	}
+	counted := map[string]bool{}
+	for _, field := range ignoreFields {
+		if counted[field] {
+			continue
+		}
+		counted[field] = true
+		matched, _, _ := dr.Filter([]string{field})
+		if matched != nil && matched.Size() > 0 {
+			ignoreFieldMatches.WithLabelValues(field).Inc()
+		}
+	}
+}
+
+// initIgnoreFieldMatches creates the series of each configured ignore field with 0,
+// so that a rule which never matches is visible in the metric.
+func initIgnoreFieldMatches(ignoreFields ...k8smanifest.ObjectFieldBindingList) {
+	for _, bindings := range ignoreFields {
+		for _, binding := range bindings {
+			for _, field := range binding.Fields {
+				ignoreFieldMatches.WithLabelValues(field).Add(0)
+			}
+		}
+	}
+}
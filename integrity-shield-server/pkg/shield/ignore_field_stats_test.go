@@ -0,0 +1,55 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+)
+
+func TestRecordIgnoreFieldMatches(t *testing.T) {
+	oldObj := []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "sample-cm"}, "data": {"comment": "a", "key1": "val1"}}`)
+	newObj := []byte(`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "sample-cm"}, "data": {"comment": "b", "key1": "val1"}}`)
+	ignoreFields := []string{"data.comment", "data.unused"}
+
+	before := testutil.ToFloat64(ignoreFieldMatches.WithLabelValues("data.comment"))
+	mutated, err := mutationCheck(oldObj, newObj, ignoreFields)
+	if err != nil || mutated {
+		t.Errorf("change in an ignored field should not be a mutation; mutated: %v, err: %v", mutated, err)
+	}
+	if count := testutil.ToFloat64(ignoreFieldMatches.WithLabelValues("data.comment")); count != before+1 {
+		t.Errorf("counter should increment when the rule suppresses a diff: %v", count)
+	}
+	if count := testutil.ToFloat64(ignoreFieldMatches.WithLabelValues("data.unused")); count != 0 {
+		t.Errorf("counter of a rule which never matches should be 0: %v", count)
+	}
+}
+
+func TestInitIgnoreFieldMatches(t *testing.T) {
+	series := testutil.CollectAndCount(ignoreFieldMatches)
+	initIgnoreFieldMatches(k8smanifest.ObjectFieldBindingList{
+		{Fields: []string{"data.never-matched"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap"}}},
+	})
+	if count := testutil.CollectAndCount(ignoreFieldMatches); count != series+1 {
+		t.Errorf("a series of the configured ignore field should be created before it matches: %d", count)
+	}
+	if count := testutil.ToFloat64(ignoreFieldMatches.WithLabelValues("data.never-matched")); count != 0 {
+		t.Errorf("counter of a rule which never matches should start with 0: %v", count)
+	}
+}
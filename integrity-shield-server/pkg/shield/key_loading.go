@@ -0,0 +1,92 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"bytes"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	log "github.com/sirupsen/logrus"
+)
+
+var keyVerifyLoadFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "integrityshield_key_verify_load_failures_total",
+	Help: "The number of failures to load a configured key at verification time.",
+}, []string{"key"})
+
+func init() {
+	prometheus.MustRegister(keyVerifyLoadFailures)
+}
+
+// checkKeyFiles removes the keys which cannot be loaded from the verify option so that
+// the verification does not silently proceed with fewer keys.
+// It returns warnings for the keys which are removed. If requireAll is true, or no keys are left,
+// it returns an error instead.
+func checkKeyFiles(vo *k8smanifest.VerifyResourceOption, requireAll bool) ([]string, error) {
+	if vo.KeyPath == "" {
+		return nil, nil
+	}
+	loadedKeys := []string{}
+	warnings := []string{}
+	for _, keyPath := range strings.Split(vo.KeyPath, ",") {
+		if err := checkKeyFile(keyPath); err != nil {
+			log.Warningf("failed to load key %s; %s", keyPath, err.Error())
+			keyVerifyLoadFailures.WithLabelValues(keyPath).Inc()
+			if requireAll {
+				return nil, errors.Wrap(err, fmt.Sprintf("failed to load required key %s", keyPath))
+			}
+			warnings = append(warnings, fmt.Sprintf("key cannot be loaded and is not used for verification: %s", keyPath))
+			continue
+		}
+		loadedKeys = append(loadedKeys, keyPath)
+	}
+	if len(loadedKeys) == 0 {
+		return nil, errors.New("no keys can be loaded for signature verification")
+	}
+	vo.KeyPath = strings.Join(loadedKeys, ",")
+	return warnings, nil
+}
+
+// checkKeyFile checks that the key file is readable and is a valid PEM if it is PEM-encoded.
+// Keys which are not local files (e.g. KMS references) are not checked.
+func checkKeyFile(keyPath string) error {
+	if strings.Contains(keyPath, "://") {
+		return nil
+	}
+	keyBytes, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return err
+	}
+	if len(bytes.TrimSpace(keyBytes)) == 0 {
+		return errors.New("key file is empty")
+	}
+	if bytes.Contains(keyBytes, []byte("-----BEGIN PGP")) {
+		return nil
+	}
+	if bytes.Contains(keyBytes, []byte("-----BEGIN")) {
+		if block, _ := pem.Decode(keyBytes); block == nil {
+			return errors.New("key file is not a valid PEM")
+		}
+	}
+	return nil
+}
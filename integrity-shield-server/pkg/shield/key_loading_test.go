@@ -0,0 +1,70 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+)
+
+func TestCheckKeyFiles(t *testing.T) {
+	dir := t.TempDir()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	pubBytes, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	goodKey := filepath.Join(dir, "good.pub")
+	_ = ioutil.WriteFile(goodKey, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubBytes}), 0644)
+	badKey := filepath.Join(dir, "bad.pub")
+	_ = ioutil.WriteFile(badKey, []byte("-----BEGIN PUBLIC KEY-----\nbroken"), 0644)
+	missingKey := filepath.Join(dir, "missing.pub")
+
+	// bad keys are removed with warnings
+	vo := &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = badKey + "," + goodKey + "," + missingKey
+	warnings, err := checkKeyFiles(vo, false)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+		return
+	}
+	if vo.KeyPath != goodKey {
+		t.Errorf("keys which cannot be loaded should be removed: %s", vo.KeyPath)
+	}
+	if len(warnings) != 2 {
+		t.Errorf("keys which cannot be loaded should be warned: %v", warnings)
+	}
+
+	// fail-closed if all keys are required
+	vo = &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = badKey + "," + goodKey
+	if _, err := checkKeyFiles(vo, true); err == nil {
+		t.Error("verification should fail if a required key cannot be loaded")
+	}
+
+	// fail-closed if no keys are left
+	vo = &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = badKey
+	if _, err := checkKeyFiles(vo, false); err == nil {
+		t.Error("verification should fail if no keys can be loaded")
+	}
+}
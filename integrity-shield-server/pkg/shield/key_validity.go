@@ -0,0 +1,97 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	log "github.com/sirupsen/logrus"
+)
+
+const defaultKeyExpiryWarningPeriod = 30 * 24 * time.Hour
+
+var keyExpiryDays = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+	Name: "integrityshield_key_expiry_days",
+	Help: "Days until the key expires. It is negative for expired keys.",
+}, []string{"key"})
+
+func init() {
+	prometheus.MustRegister(keyExpiryDays)
+}
+
+// applyKeyValidity removes the keys outside their validity windows from the verify option.
+// The windows are extended by the clock skew allowance on both ends.
+// It returns warnings for the keys which expire soon, and an error if no valid keys are left.
+func applyKeyValidity(vo *k8smanifest.VerifyResourceOption, validityList []k8smnfconfig.KeyValidity, warningPeriod, skew time.Duration, now time.Time) ([]string, error) {
+	if len(validityList) == 0 || vo.KeyPath == "" {
+		return nil, nil
+	}
+	if warningPeriod == 0 {
+		warningPeriod = defaultKeyExpiryWarningPeriod
+	}
+	validKeys := []string{}
+	warnings := []string{}
+	for _, keyPath := range strings.Split(vo.KeyPath, ",") {
+		validity, keyRef := getKeyValidity(keyPath, validityList)
+		if validity == nil {
+			validKeys = append(validKeys, keyPath)
+			continue
+		}
+		if validity.NotBefore != nil && now.Add(skew).Before(validity.NotBefore.Time) {
+			log.Warning("key is not valid yet: ", keyRef)
+			continue
+		}
+		if validity.NotAfter != nil {
+			remaining := validity.NotAfter.Time.Sub(now)
+			keyExpiryDays.WithLabelValues(keyRef).Set(remaining.Hours() / 24)
+			if remaining+skew <= 0 {
+				log.Warning("key is expired: ", keyRef)
+				continue
+			}
+			if remaining < warningPeriod {
+				warnings = append(warnings, fmt.Sprintf("key expires soon: %s expires at %s", keyRef, validity.NotAfter.Time.UTC().Format(time.RFC3339)))
+			}
+		}
+		validKeys = append(validKeys, keyPath)
+	}
+	if len(validKeys) == 0 {
+		return nil, errors.New("no valid keys for signature verification; all keys are expired or not valid yet")
+	}
+	vo.KeyPath = strings.Join(validKeys, ",")
+	return warnings, nil
+}
+
+// getKeyValidity returns the validity of the key and the reference of the key used in logs and metrics;
+// the key path, or `<namespace>/<name>` of the secret for a key loaded from a secret.
+func getKeyValidity(keyPath string, validityList []k8smnfconfig.KeyValidity) (*k8smnfconfig.KeyValidity, string) {
+	for i := range validityList {
+		v := validityList[i]
+		if v.KeyPath != "" && v.KeyPath == keyPath {
+			return &validityList[i], keyPath
+		}
+		if v.KeySecretName != "" && strings.HasPrefix(keyPath, k8smnfconfig.GetKeySecretDir(v.KeySecretNamespace, v.KeySecretName)) {
+			return &validityList[i], fmt.Sprintf("%s/%s", v.KeySecretNamespace, v.KeySecretName)
+		}
+	}
+	return nil, keyPath
+}
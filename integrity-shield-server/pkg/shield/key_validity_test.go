@@ -0,0 +1,113 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestApplyKeyValidity(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	expired := metav1.NewTime(now.Add(-time.Hour))
+	nearExpiry := metav1.NewTime(now.Add(7 * 24 * time.Hour))
+	validityList := []k8smnfconfig.KeyValidity{
+		{KeyPath: "/keys/old.pub", NotAfter: &expired},
+		{KeyPath: "/keys/current.pub", NotAfter: &nearExpiry},
+	}
+
+	// expired key is denied
+	vo := &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = "/keys/old.pub"
+	if _, err := applyKeyValidity(vo, validityList, 0, 0, now); err == nil {
+		t.Error("verification with only an expired key should be denied")
+	}
+
+	// near-expiry key is warned
+	vo = &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = "/keys/old.pub,/keys/current.pub"
+	warnings, err := applyKeyValidity(vo, validityList, 0, 0, now)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err.Error())
+		return
+	}
+	if vo.KeyPath != "/keys/current.pub" {
+		t.Errorf("expired key should be removed: %s", vo.KeyPath)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("near-expiry key should be warned: %v", warnings)
+	}
+}
+
+func TestApplyKeyValidityWithClockSkew(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	skew := 5 * time.Minute
+	justExpired := metav1.NewTime(now.Add(-time.Minute))
+	justStarted := metav1.NewTime(now.Add(time.Minute))
+	validityList := []k8smnfconfig.KeyValidity{
+		{KeyPath: "/keys/old.pub", NotAfter: &justExpired},
+		{KeyPath: "/keys/new.pub", NotBefore: &justStarted},
+	}
+
+	for _, keyPath := range []string{"/keys/old.pub", "/keys/new.pub"} {
+		vo := &k8smanifest.VerifyResourceOption{}
+		vo.KeyPath = keyPath
+		if _, err := applyKeyValidity(vo, validityList, 0, 0, now); err == nil {
+			t.Errorf("key at the boundary should be denied without the allowance: %s", keyPath)
+		}
+		vo = &k8smanifest.VerifyResourceOption{}
+		vo.KeyPath = keyPath
+		if _, err := applyKeyValidity(vo, validityList, 0, skew, now); err != nil {
+			t.Errorf("key at the boundary should be allowed with the allowance: %s", keyPath)
+		}
+	}
+}
+
+func TestApplyKeyValidityWithKeySecret(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	expired := metav1.NewTime(now.Add(-time.Hour))
+	nearExpiry := metav1.NewTime(now.Add(7 * 24 * time.Hour))
+	validityList := []k8smnfconfig.KeyValidity{
+		{KeySecretNamespace: "ishield", KeySecretName: "old-key", NotAfter: &expired},
+		{KeySecretNamespace: "ishield", KeySecretName: "current-key", NotAfter: &nearExpiry},
+	}
+	oldKeyPath := filepath.Join(k8smnfconfig.GetKeySecretDir("ishield", "old-key"), "cosign.pub")
+	currentKeyPath := filepath.Join(k8smnfconfig.GetKeySecretDir("ishield", "current-key"), "cosign.pub")
+
+	vo := &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = oldKeyPath + "," + currentKeyPath
+	warnings, err := applyKeyValidity(vo, validityList, 0, 0, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if vo.KeyPath != currentKeyPath {
+		t.Errorf("key in the expired secret should be removed: %s", vo.KeyPath)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "ishield/current-key") {
+		t.Errorf("near-expiry key should be warned with the secret reference: %v", warnings)
+	}
+	if days := testutil.ToFloat64(keyExpiryDays.WithLabelValues("ishield/current-key")); days != 7 {
+		t.Errorf("expiry days should be labeled with the secret reference: %v", days)
+	}
+}
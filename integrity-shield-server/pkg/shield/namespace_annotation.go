@@ -0,0 +1,166 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"sync"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/kubeutil"
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const (
+	NamespaceLastDeniedTimeAnnotationKey = "integrityshield.io/lastDeniedTime"
+	NamespaceDeniedCountAnnotationKey    = "integrityshield.io/deniedCount"
+)
+
+const defaultNamespaceAnnotationLimit = 1 * time.Minute
+
+// denials which are not reflected to the namespace annotation yet because of the rate limit,
+// and the last time when the annotation of each namespace is updated
+var pendingNamespaceDenials = map[string]int{}
+var lastNamespaceAnnotatedTime = map[string]time.Time{}
+var pendingNamespaceDenialsLock sync.Mutex
+
+// newNamespaceClient can be replaced in tests
+var newNamespaceClient = func() (kubeclient.Interface, error) {
+	kubeconf, err := kubeutil.GetKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	return kubeclient.NewForConfig(kubeconf)
+}
+
+func annotateDeniedNamespace(req admission.Request, ar *ResultFromRequestHandler, config k8smnfconfig.SideEffectConfig) error {
+	// only denied requests in a namespace are recorded
+	if ar.Allow || req.Namespace == "" {
+		return nil
+	}
+	// no side effect for dry-run requests
+	if req.DryRun != nil && *req.DryRun {
+		return nil
+	}
+
+	limit := config.NamespaceAnnotationLimit.Duration
+	if limit == 0 {
+		limit = defaultNamespaceAnnotationLimit
+	}
+	// the rate limit is checked first, and the lock is not held during the API calls
+	denied, ok := takePendingNamespaceDenials(req.Namespace, time.Now(), limit)
+	if !ok {
+		return nil
+	}
+	updated, err := patchDenySummaryAnnotations(req.Namespace, denied, limit)
+	if err != nil || !updated {
+		restorePendingNamespaceDenials(req.Namespace, denied)
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"namespace": req.Namespace,
+		"name":      req.Name,
+		"kind":      req.Kind.Kind,
+		"operation": req.Operation,
+	}).Debug("Namespace is annotated with deny summary")
+	return nil
+}
+
+// takePendingNamespaceDenials counts a denial in the namespace. If the namespace is not annotated within
+// the limit, it returns the pending denials to be reflected to the annotation and resets them.
+func takePendingNamespaceDenials(namespace string, now time.Time, limit time.Duration) (int, bool) {
+	pendingNamespaceDenialsLock.Lock()
+	defer pendingNamespaceDenialsLock.Unlock()
+	pendingNamespaceDenials[namespace] = pendingNamespaceDenials[namespace] + 1
+	if last, found := lastNamespaceAnnotatedTime[namespace]; found && now.Sub(last) < limit {
+		return 0, false
+	}
+	denied := pendingNamespaceDenials[namespace]
+	delete(pendingNamespaceDenials, namespace)
+	lastNamespaceAnnotatedTime[namespace] = now
+	return denied, true
+}
+
+// restorePendingNamespaceDenials keeps the denials which failed to be reflected for the next update.
+func restorePendingNamespaceDenials(namespace string, denied int) {
+	pendingNamespaceDenialsLock.Lock()
+	defer pendingNamespaceDenialsLock.Unlock()
+	pendingNamespaceDenials[namespace] = pendingNamespaceDenials[namespace] + denied
+}
+
+// patchDenySummaryAnnotations adds the denials to the summary annotations of the namespace.
+// It returns false if the annotation was updated within the limit, e.g. by another replica.
+func patchDenySummaryAnnotations(namespace string, denied int, limit time.Duration) (bool, error) {
+	client, err := newNamespaceClient()
+	if err != nil {
+		return false, err
+	}
+	ns, err := client.CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+	if err != nil {
+		log.Warningf("failed to get namespace `%s` to record the denial; %s", namespace, err.Error())
+		return false, err
+	}
+	annotations, updated := updateDenySummaryAnnotations(ns.GetAnnotations(), denied, time.Now(), limit)
+	if !updated {
+		return false, nil
+	}
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": annotations,
+		},
+	}
+	patchBytes, _ := json.Marshal(patch)
+	_, err = client.CoreV1().Namespaces().Patch(context.Background(), namespace, types.MergePatchType, patchBytes, metav1.PatchOptions{})
+	if err != nil {
+		if k8serrors.IsForbidden(err) {
+			log.Warningf("IntegrityShield is not allowed to annotate namespace `%s`; %s", namespace, err.Error())
+			return false, nil
+		}
+		log.Errorf("failed to annotate namespace `%s`; %s", namespace, err.Error())
+		return false, err
+	}
+	return true, nil
+}
+
+// updateDenySummaryAnnotations returns the summary annotations to be set on a namespace.
+// It returns false if the last update is more recent than the limit.
+func updateDenySummaryAnnotations(current map[string]string, denied int, now time.Time, limit time.Duration) (map[string]string, bool) {
+	if lastStr, ok := current[NamespaceLastDeniedTimeAnnotationKey]; ok {
+		last, err := time.Parse(time.RFC3339, lastStr)
+		if err == nil && now.Sub(last) < limit {
+			return nil, false
+		}
+	}
+	count := 0
+	if countStr, ok := current[NamespaceDeniedCountAnnotationKey]; ok {
+		count, _ = strconv.Atoi(countStr)
+	}
+	annotations := map[string]string{
+		NamespaceLastDeniedTimeAnnotationKey: now.UTC().Format(time.RFC3339),
+		NamespaceDeniedCountAnnotationKey:    strconv.Itoa(count + denied),
+	}
+	return annotations, true
+}
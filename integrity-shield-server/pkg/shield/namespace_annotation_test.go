@@ -0,0 +1,134 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	admv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestUpdateDenySummaryAnnotations(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	limit := 1 * time.Minute
+
+	// first denial in the namespace
+	annotations, updated := updateDenySummaryAnnotations(map[string]string{}, 1, now, limit)
+	if !updated {
+		t.Error("annotations should be updated for the first denial")
+		return
+	}
+	if annotations[NamespaceDeniedCountAnnotationKey] != "1" {
+		t.Errorf("unexpected denied count: %s", annotations[NamespaceDeniedCountAnnotationKey])
+	}
+	if annotations[NamespaceLastDeniedTimeAnnotationKey] != "2021-09-01T12:00:00Z" {
+		t.Errorf("unexpected last denied time: %s", annotations[NamespaceLastDeniedTimeAnnotationKey])
+	}
+
+	// rate limited
+	_, updated = updateDenySummaryAnnotations(annotations, 1, now.Add(30*time.Second), limit)
+	if updated {
+		t.Error("annotations should not be updated within the limit")
+	}
+
+	// pending denials are accumulated
+	annotations, updated = updateDenySummaryAnnotations(annotations, 2, now.Add(2*time.Minute), limit)
+	if !updated {
+		t.Error("annotations should be updated after the limit")
+		return
+	}
+	if annotations[NamespaceDeniedCountAnnotationKey] != "3" {
+		t.Errorf("unexpected denied count: %s", annotations[NamespaceDeniedCountAnnotationKey])
+	}
+}
+
+func TestAnnotateDeniedNamespace(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sample-ns"}})
+	orgFunc := newNamespaceClient
+	defer func() { newNamespaceClient = orgFunc }()
+	newNamespaceClient = func() (kubeclient.Interface, error) { return client, nil }
+	pendingNamespaceDenials = map[string]int{}
+	lastNamespaceAnnotatedTime = map[string]time.Time{}
+
+	req := admission.Request{AdmissionRequest: admv1.AdmissionRequest{Namespace: "sample-ns", Name: "sample-cm", Operation: admv1.Create}}
+	denied := &ResultFromRequestHandler{Allow: false, Message: "no signature found"}
+	config := k8smnfconfig.SideEffectConfig{AnnotateNamespaceOnDeny: true}
+	if err := annotateDeniedNamespace(req, denied, config); err != nil {
+		t.Fatalf("failed to annotate namespace: %s", err.Error())
+	}
+	ns, _ := client.CoreV1().Namespaces().Get(context.Background(), "sample-ns", metav1.GetOptions{})
+	if ns.GetAnnotations()[NamespaceDeniedCountAnnotationKey] != "1" {
+		t.Errorf("denied count should be annotated: %v", ns.GetAnnotations())
+	}
+	if _, found := ns.GetAnnotations()[NamespaceLastDeniedTimeAnnotationKey]; !found {
+		t.Errorf("last denied time should be annotated: %v", ns.GetAnnotations())
+	}
+
+	// rate limited; the namespace is not fetched
+	actions := len(client.Actions())
+	if err := annotateDeniedNamespace(req, denied, config); err != nil {
+		t.Fatalf("failed to annotate namespace: %s", err.Error())
+	}
+	if len(client.Actions()) != actions {
+		t.Errorf("no API call is expected within the rate limit: %v", client.Actions()[actions:])
+	}
+	if pendingNamespaceDenials["sample-ns"] != 1 {
+		t.Errorf("the denial should be pending: %d", pendingNamespaceDenials["sample-ns"])
+	}
+
+	// allowed requests are not recorded
+	if err := annotateDeniedNamespace(req, &ResultFromRequestHandler{Allow: true}, config); err != nil || len(client.Actions()) != actions {
+		t.Error("allowed request should not be recorded")
+	}
+}
+
+func TestAuditedDenialIsNotRecordedOnNamespace(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sample-ns"}})
+	orgFunc := newNamespaceClient
+	defer func() { newNamespaceClient = orgFunc }()
+	newNamespaceClient = func() (kubeclient.Interface, error) { return client, nil }
+	pendingNamespaceDenials = map[string]int{}
+	lastNamespaceAnnotatedTime = map[string]time.Time{}
+
+	req := admission.Request{AdmissionRequest: admv1.AdmissionRequest{Namespace: "sample-ns", Name: "sample-cm", Operation: admv1.Create}}
+	config := k8smnfconfig.SideEffectConfig{AnnotateNamespaceOnDeny: true}
+
+	// the denial is allowed by the audit level before the side effects
+	r := &ResultFromRequestHandler{Allow: false, Message: "no signature found"}
+	applyEnforcementLevel(r, k8smnfconfig.EnforcementLevelAudit)
+	emitDenySideEffects(req, r, "", config)
+	if len(client.Actions()) != 0 || len(pendingNamespaceDenials) != 0 {
+		t.Errorf("request allowed by the audit level should not be recorded as a denial: %v", client.Actions())
+	}
+
+	// an enforced denial is recorded
+	r = &ResultFromRequestHandler{Allow: false, Message: "no signature found"}
+	applyEnforcementLevel(r, "")
+	emitDenySideEffects(req, r, "", config)
+	ns, _ := client.CoreV1().Namespaces().Get(context.Background(), "sample-ns", metav1.GetOptions{})
+	if ns.GetAnnotations()[NamespaceDeniedCountAnnotationKey] != "1" {
+		t.Errorf("denied request should be recorded: %v", ns.GetAnnotations())
+	}
+}
@@ -0,0 +1,58 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"context"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/kubeutil"
+	log "github.com/sirupsen/logrus"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubeclient "k8s.io/client-go/kubernetes"
+)
+
+// getNamespaceLabels returns the labels of a namespace. It can be replaced in tests.
+var getNamespaceLabels = func(namespace string) (map[string]string, error) {
+	config, err := kubeutil.GetKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	ns, err := clientset.CoreV1().Namespaces().Get(context.Background(), namespace, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	return ns.GetLabels(), nil
+}
+
+// getOverlayIgnoreFields returns the overlay fields for the environment of the namespace.
+func getOverlayIgnoreFields(config k8smnfconfig.OverlayConfig, namespace string) k8smanifest.ObjectFieldBindingList {
+	if namespace == "" || config.NamespaceLabelKey == "" {
+		return nil
+	}
+	nsLabels, err := getNamespaceLabels(namespace)
+	if err != nil {
+		log.Errorf("failed to get labels of namespace `%s`; %s", namespace, err.Error())
+		return nil
+	}
+	return config.GetIgnoreFields(nsLabels)
+}
@@ -0,0 +1,71 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/json"
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+)
+
+func TestOverlayIgnoreFields(t *testing.T) {
+	orgFunc := getNamespaceLabels
+	defer func() { getNamespaceLabels = orgFunc }()
+	getNamespaceLabels = func(namespace string) (map[string]string, error) {
+		return map[string]string{"env": "prod"}, nil
+	}
+
+	overlay := k8smnfconfig.OverlayConfig{
+		NamespaceLabelKey: "env",
+		Profiles: map[string]k8smanifest.ObjectFieldBindingList{
+			"prod": {
+				{
+					Fields:  []string{"spec.replicas"},
+					Objects: k8smanifest.ObjectReferenceList{{Kind: "Deployment"}},
+				},
+			},
+		},
+	}
+
+	base := loadTestObject(t, testDeployment)
+	prod := base.DeepCopy()
+	prod.Object["spec"].(map[string]interface{})["replicas"] = int64(5)
+
+	_, fields := getOverlayIgnoreFields(overlay, "sample-ns").Match(*prod)
+	baseBytes, _ := json.Marshal(base.Object)
+	prodBytes, _ := json.Marshal(prod.Object)
+	mutated, err := mutationCheck(baseBytes, prodBytes, fields)
+	if err != nil {
+		t.Errorf("failed to compare objects: %s", err.Error())
+		return
+	}
+	if mutated {
+		t.Errorf("scaled replicas should be tolerated by the prod overlay; fields: %v", fields)
+	}
+
+	// no overlay for the other environment
+	getNamespaceLabels = func(namespace string) (map[string]string, error) {
+		return map[string]string{"env": "dev"}, nil
+	}
+	_, fields = getOverlayIgnoreFields(overlay, "sample-ns").Match(*prod)
+	mutated, _ = mutationCheck(baseBytes, prodBytes, fields)
+	if !mutated {
+		t.Error("scaled replicas should be detected without overlay")
+	}
+}
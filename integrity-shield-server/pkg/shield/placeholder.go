@@ -0,0 +1,64 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"regexp"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+	log "github.com/sirupsen/logrus"
+)
+
+// getResolvedPlaceholderFields returns the fields of the diff whose value in one side is a placeholder
+// and whose value in the other side matches the pattern of the placeholder.
+// The second return value is true only if all differences are such resolved placeholders.
+func getResolvedPlaceholderFields(diff *mapnode.DiffResult, placeholders []k8smnfconfig.PlaceholderPattern) ([]string, bool) {
+	if diff == nil || diff.Size() == 0 || len(placeholders) == 0 {
+		return nil, false
+	}
+	patterns := map[string]*regexp.Regexp{}
+	for _, p := range placeholders {
+		re, err := regexp.Compile(fmt.Sprintf("^(?:%s)$", p.Pattern))
+		if err != nil {
+			log.Warningf("failed to compile the pattern of placeholder %s; %s", p.Placeholder, err.Error())
+			continue
+		}
+		patterns[p.Placeholder] = re
+	}
+	fields := []string{}
+	all := true
+	for _, item := range diff.Items {
+		before := fmt.Sprint(item.Values["before"])
+		after := fmt.Sprint(item.Values["after"])
+		if isResolvedPlaceholder(before, after, patterns) || isResolvedPlaceholder(after, before, patterns) {
+			fields = append(fields, item.Key)
+			continue
+		}
+		all = false
+	}
+	return fields, all
+}
+
+func isResolvedPlaceholder(placeholder, value string, patterns map[string]*regexp.Regexp) bool {
+	re, ok := patterns[placeholder]
+	if !ok {
+		return false
+	}
+	return re.MatchString(value)
+}
@@ -0,0 +1,47 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+func TestGetResolvedPlaceholderFields(t *testing.T) {
+	placeholders := []k8smnfconfig.PlaceholderPattern{
+		{Placeholder: "${CONFIG_HASH}", Pattern: "[a-f0-9]{8}"},
+	}
+	signed, _ := mapnode.NewFromBytes([]byte(`{"kind": "ConfigMap", "metadata": {"name": "sample-cm"}, "data": {"hash": "${CONFIG_HASH}", "key1": "val1"}}`))
+
+	resolved, _ := mapnode.NewFromBytes([]byte(`{"kind": "ConfigMap", "metadata": {"name": "sample-cm"}, "data": {"hash": "1a2b3c4d", "key1": "val1"}}`))
+	fields, ok := getResolvedPlaceholderFields(resolved.Diff(signed), placeholders)
+	if !ok || len(fields) != 1 || fields[0] != "data.hash" {
+		t.Errorf("resolved value matching the pattern should pass; fields: %v", fields)
+	}
+
+	invalid, _ := mapnode.NewFromBytes([]byte(`{"kind": "ConfigMap", "metadata": {"name": "sample-cm"}, "data": {"hash": "not-a-hash", "key1": "val1"}}`))
+	if _, ok := getResolvedPlaceholderFields(invalid.Diff(signed), placeholders); ok {
+		t.Error("resolved value not matching the pattern should fail")
+	}
+
+	changed, _ := mapnode.NewFromBytes([]byte(`{"kind": "ConfigMap", "metadata": {"name": "sample-cm"}, "data": {"hash": "1a2b3c4d", "key1": "changed"}}`))
+	if _, ok := getResolvedPlaceholderFields(changed.Diff(signed), placeholders); ok {
+		t.Error("diff other than placeholders should fail")
+	}
+}
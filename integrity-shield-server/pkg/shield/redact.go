@@ -0,0 +1,85 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/json"
+	"strings"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const redactedValue = "REDACTED"
+
+var secretRedactFields = k8smanifest.ObjectFieldBinding{
+	Fields: []string{"data", "stringData"},
+	Objects: k8smanifest.ObjectReferenceList{
+		k8smanifest.ObjectReference{Kind: "Secret"},
+	},
+}
+
+// annotations of a Secret which contain a copy of its data; the last applied object and the signed manifest
+var secretRedactAnnotations = []string{
+	"kubectl.kubernetes.io/last-applied-configuration",
+	AnnotationKeyDomain + "/message",
+	"cosign.sigstore.dev/message",
+}
+
+// logRequestObject logs a requested object at debug level with the sensitive fields redacted.
+func logRequestObject(resource unstructured.Unstructured, config k8smnfconfig.LogConfig) {
+	if !log.IsLevelEnabled(log.DebugLevel) {
+		return
+	}
+	redacted := redactObject(resource, config.RedactFields)
+	objBytes, _ := json.Marshal(redacted.Object)
+	log.WithFields(log.Fields{
+		"namespace": resource.GetNamespace(),
+		"name":      resource.GetName(),
+		"kind":      resource.GetKind(),
+	}).Debug("Requested object: ", string(objBytes))
+}
+
+// redactObject returns a copy of the object whose sensitive fields are replaced.
+func redactObject(resource unstructured.Unstructured, redactFields k8smanifest.ObjectFieldBindingList) *unstructured.Unstructured {
+	fieldBindings := k8smanifest.ObjectFieldBindingList{secretRedactFields}
+	fieldBindings = append(fieldBindings, redactFields...)
+	_, fields := fieldBindings.Match(resource)
+	redacted := resource.DeepCopy()
+	for _, field := range fields {
+		path := strings.Split(field, ".")
+		if _, found, _ := unstructured.NestedFieldNoCopy(redacted.Object, path...); !found {
+			continue
+		}
+		_ = unstructured.SetNestedField(redacted.Object, redactedValue, path...)
+	}
+	// annotation keys contain dots, so they are not matched as fields
+	if resource.GetKind() == "Secret" {
+		annotations := redacted.GetAnnotations()
+		for _, key := range secretRedactAnnotations {
+			if _, found := annotations[key]; found {
+				annotations[key] = redactedValue
+			}
+		}
+		if len(annotations) > 0 {
+			redacted.SetAnnotations(annotations)
+		}
+	}
+	return redacted
+}
@@ -0,0 +1,76 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const testSecret = `{
+	"apiVersion": "v1",
+	"kind": "Secret",
+	"metadata": {
+		"name": "sample-secret",
+		"namespace": "sample-ns",
+		"annotations": {
+			"kubectl.kubernetes.io/last-applied-configuration": "{\"data\":{\"password\":\"c2VjcmV0LXBhc3N3b3Jk\"}}",
+			"cosign.sigstore.dev/message": "H4sIAAAAAAAA/secret-message",
+			"sample-annotation": "sample-value"
+		}
+	},
+	"data": {
+		"password": "c2VjcmV0LXBhc3N3b3Jk"
+	},
+	"stringData": {
+		"token": "secret-token"
+	}
+}`
+
+func TestLogRequestObjectRedactsSecretData(t *testing.T) {
+	buf := new(bytes.Buffer)
+	orgLevel := log.GetLevel()
+	log.SetOutput(buf)
+	log.SetLevel(log.DebugLevel)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetLevel(orgLevel)
+	}()
+
+	obj := loadTestObject(t, testSecret)
+	logRequestObject(obj, k8smnfconfig.LogConfig{})
+	out := buf.String()
+	if strings.Contains(out, "c2VjcmV0LXBhc3N3b3Jk") || strings.Contains(out, "secret-token") {
+		t.Errorf("secret data should be redacted: %s", out)
+	}
+	if strings.Contains(out, "secret-message") {
+		t.Errorf("signed message of secret should be redacted: %s", out)
+	}
+	if !strings.Contains(out, redactedValue) || !strings.Contains(out, "sample-secret") || !strings.Contains(out, "sample-value") {
+		t.Errorf("metadata should be preserved in the log: %s", out)
+	}
+	// the requested object itself is not changed
+	if data, _, _ := unstructured.NestedString(obj.Object, "stringData", "token"); data != "secret-token" {
+		t.Errorf("requested object should not be changed: %s", data)
+	}
+}
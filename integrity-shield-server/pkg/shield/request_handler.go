@@ -17,11 +17,13 @@
 package shield
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
+	"text/template"
 	"time"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -30,6 +32,7 @@ import (
 	"github.com/ghodss/yaml"
 	"github.com/pkg/errors"
 	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	k8smnfutil "github.com/sigstore/k8s-manifest-sigstore/pkg/util"
 	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/kubeutil"
 	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
 	log "github.com/sirupsen/logrus"
@@ -49,6 +52,9 @@ const defaultHandlerConfigMapName = "request-handler-config"
 const ImageRefAnnotationKeyShield = "integrityshield.io/signature"
 const AnnotationKeyDomain = "integrityshield.io"
 const SignatureAnnotationTypeShield = "IntegrityShield"
+const ReasonObjectDecodeError = "OBJECT_DECODE_ERROR"
+const ReasonStaleSignature = "STALE_SIGNATURE"
+const ReasonVerificationTimeout = "VERIFICATION_TIMEOUT"
 const (
 	EventTypeAnnotationKey       = "integrityshield.io/eventType"
 	EventResultAnnotationKey     = "integrityshield.io/eventResult"
@@ -57,20 +63,6 @@ const (
 )
 
 func RequestHandler(req admission.Request, paramObj *k8smnfconfig.ParameterObject) *ResultFromRequestHandler {
-	// unmarshal admission request object
-	// load Resource from Admission request
-	var resource unstructured.Unstructured
-	objectBytes := req.AdmissionRequest.Object.Raw
-	err := json.Unmarshal(objectBytes, &resource)
-	if err != nil {
-		log.Errorf("failed to Unmarshal a requested object into %T; %s", resource, err.Error())
-		errMsg := "IntegrityShield failed to decide the response. Failed to Unmarshal a requested object: " + err.Error()
-		return &ResultFromRequestHandler{
-			Allow:   false,
-			Message: errMsg,
-		}
-	}
-
 	// load request handler config
 	rhconfig, err := LoadRequestHandlerConfig()
 	if err != nil {
@@ -86,6 +78,28 @@ func RequestHandler(req admission.Request, paramObj *k8smnfconfig.ParameterObjec
 		rhconfig = &k8smnfconfig.RequestHandlerConfig{}
 	}
 
+	// operations other than CREATE, UPDATE and DELETE, e.g. CONNECT for exec
+	switch getOperationAction(req, rhconfig.OperationRules) {
+	case k8smnfconfig.OperationActionAllow:
+		return &ResultFromRequestHandler{
+			Allow:   true,
+			Message: fmt.Sprintf("%s operation is allowed without verification.", req.Operation),
+		}
+	case k8smnfconfig.OperationActionDeny:
+		return &ResultFromRequestHandler{
+			Allow:   false,
+			Message: fmt.Sprintf("%s operation is denied by operation rules.", req.Operation),
+		}
+	}
+
+	// unmarshal admission request object
+	// load Resource from Admission request
+	resource, err := decodeRequestObject(req.AdmissionRequest.Object.Raw)
+	if err != nil {
+		log.Errorf("failed to Unmarshal a requested object into %T; %s", resource, err.Error())
+		return newDecodeErrorResult(err, rhconfig.FailurePolicy)
+	}
+
 	// setup log
 	k8smnfconfig.SetupLogger(rhconfig.Log, req)
 
@@ -104,44 +118,36 @@ func RequestHandler(req admission.Request, paramObj *k8smnfconfig.ParameterObjec
 		"operation": req.Operation,
 		"userName":  req.UserInfo.Username,
 	}).Debug("Parameter", paramObj)
+	logRequestObject(resource, rhconfig.Log)
 
 	commonSkipUserMatched := false
 	skipObjectMatched := false
 
 	//filter by user listed in common profile
-	commonSkipUserMatched = rhconfig.RequestFilterProfile.SkipUsers.Match(resource, req.AdmissionRequest.UserInfo.Username)
+	commonSkipUserMatched = rhconfig.RequestFilterProfile.SkipUsers.Match(resource, req.AdmissionRequest.UserInfo.Username, req.AdmissionRequest.UserInfo.Groups)
 	// skip object
 	skipObjectMatched = skipObjectsMatch(rhconfig.RequestFilterProfile.SkipObjects, resource)
 
 	// Proccess with parameter
 	//filter by user
-	skipUserMatched := paramObj.SkipUsers.Match(resource, req.AdmissionRequest.UserInfo.Username)
+	skipUserMatched := paramObj.SkipUsers.Match(resource, req.AdmissionRequest.UserInfo.Username, req.AdmissionRequest.UserInfo.Groups)
 
 	//check scope
 	inScopeObjMatched := paramObj.InScopeObjects.Match(resource)
 
+	// series of the configured ignore fields start with 0
+	initIgnoreFieldMatches(paramObj.IgnoreFields, rhconfig.RequestFilterProfile.IgnoreFields)
+
 	// mutation check
-	if isUpdateRequest(req.AdmissionRequest.Operation) {
-		ignoreFields := getMatchedIgnoreFields(paramObj.IgnoreFields, rhconfig.RequestFilterProfile.IgnoreFields, resource)
-		mutated, err := mutationCheck(req.AdmissionRequest.OldObject.Raw, req.AdmissionRequest.Object.Raw, ignoreFields)
-		if err != nil {
-			log.Errorf("failed to check mutation", err.Error())
-			errMsg := "IntegrityShield failed to decide the response. Failed to check mutation: " + err.Error()
-			return &ResultFromRequestHandler{
-				Allow:   false,
-				Message: errMsg,
-			}
-		}
-		if !mutated {
-			return &ResultFromRequestHandler{
-				Allow:   true,
-				Message: "no mutation found",
-			}
-		}
+	if r := checkUpdateMutation(req, resource, paramObj, rhconfig); r != nil {
+		return r
 	}
 
 	allow := false
 	message := ""
+	reason := ""
+	var detail *VerificationDetail
+	var keyWarnings []string
 	if skipUserMatched || commonSkipUserMatched {
 		allow = true
 		message = "SkipUsers rule matched."
@@ -151,6 +157,15 @@ func RequestHandler(req admission.Request, paramObj *k8smnfconfig.ParameterObjec
 	} else if skipObjectMatched {
 		allow = true
 		message = "SkipObjects rule matched."
+	} else if rhconfig.RequestFilterProfile.SkipOwnedObjects && isOwnedByController(resource, paramObj.InScopeObjects, req.AdmissionRequest.UserInfo.Username, rhconfig.RequestFilterProfile.OwnedObjectControllers) {
+		allow = true
+		message = "owned by a controller. The owner object is verified instead."
+	} else if !rhconfig.RequestFilterProfile.ApiGroups.Match(req.Kind.Group) {
+		allow = true
+		message = "ApiGroups filter did not match. Out of scope of verification."
+	} else if rhconfig.EnforcementCutoff != nil && isCreatedBeforeCutoff(req, resource, rhconfig.EnforcementCutoff.Time, rhconfig.ClockSkewAllowance.Duration) {
+		allow = true
+		message = "created before the enforcement cutoff. This request is audited but not verified."
 	} else {
 		var signatureAnnotationType string
 		annotations := resource.GetAnnotations()
@@ -158,9 +173,47 @@ func RequestHandler(req admission.Request, paramObj *k8smnfconfig.ParameterObjec
 		if found {
 			signatureAnnotationType = SignatureAnnotationTypeShield
 		}
-		vo := setVerifyOption(paramObj, rhconfig, signatureAnnotationType)
+		vo := setVerifyOption(paramObj, rhconfig, signatureAnnotationType, req.Namespace)
+		appendRequestIgnoreFields(vo, req, resource, rhconfig)
 		// call VerifyResource with resource, verifyOption, keypath, imageRef
-		result, err := k8smanifest.VerifyResource(resource, vo)
+		requiredSignatures := rhconfig.ImageVerificationConfig.RequiredSignatures
+		validSignatures := 0
+		var result *k8smanifest.VerifyResourceResult
+		var manifestRef string
+		manifestRef, err = getManifestRefFromAnnotation(resource, rhconfig.ManifestRefAnnotation, vo.IgnoreFields)
+		if err == nil && manifestRef == "" {
+			manifestRef, err = getManifestRefFromLabels(resource, rhconfig.ManifestRefTemplate, vo.IgnoreFields)
+		}
+		if manifestRef != "" {
+			vo.ImageRef = manifestRef
+		}
+		verifyBreaker.configure(rhconfig.CircuitBreaker)
+		if err == nil {
+			keyWarnings, err = applyKeyValidity(vo, rhconfig.KeyValidityList, rhconfig.KeyExpiryWarningPeriod.Duration, rhconfig.ClockSkewAllowance.Duration, time.Now())
+		}
+		if err == nil {
+			var loadWarnings []string
+			loadWarnings, err = checkKeyFiles(vo, rhconfig.RequireAllKeys)
+			keyWarnings = append(keyWarnings, loadWarnings...)
+		}
+		if err == nil && rhconfig.DigestVerification && hasManifestDigest(resource) {
+			result, err = verifyResourceWithDigest(resource, vo, requiredSignatures)
+		} else if err == nil && requiredSignatures > 1 {
+			result, validSignatures, err = verifyResourceWithThreshold(resource, vo, requiredSignatures, rhconfig.VerifyTimeout.Duration)
+		} else if err == nil {
+			result, err = verifyResourceWithTimeout(resource, vo, rhconfig.VerifyTimeout.Duration)
+		}
+		// verify again only once ignoring the differences tolerated by the rules
+		if err == nil && result != nil && !result.Verified {
+			var fields []string
+			var ok bool
+			fields, ok, err = getToleratedFields(resource, result.Diff, vo.IgnoreFields, rhconfig)
+			if err == nil && ok {
+				recordIgnoreFieldMatches(result.Diff, fields)
+				vo.IgnoreFields = append(vo.IgnoreFields, newToleratedIgnoreField(resource, fields))
+				result, validSignatures, err = reverifyResource(resource, vo, requiredSignatures, rhconfig.VerifyTimeout.Duration)
+			}
+		}
 		log.WithFields(log.Fields{
 			"namespace": req.Namespace,
 			"name":      req.Name,
@@ -179,25 +232,35 @@ func RequestHandler(req admission.Request, paramObj *k8smnfconfig.ParameterObjec
 			r := &ResultFromRequestHandler{
 				Allow:   false,
 				Message: err.Error(),
+				Detail:  newVerificationDetail(nil, err),
+			}
+			if isVerificationTimeout(err) {
+				r.Reason = ReasonVerificationTimeout
 			}
-			// generate events
-			if rhconfig.SideEffectConfig.CreateDenyEvent {
-				_ = createOrUpdateEvent(req, r, paramObj.ConstraintName)
+			if errors.Cause(err) == errVerificationCircuitOpen && rhconfig.FailurePolicy == k8smnfconfig.FailurePolicyIgnore {
+				r.Allow = true
+				r.Message = "allowed by failure policy: " + err.Error()
 			}
+			applyEnforcementLevel(r, rhconfig.EnforcementConfig.GetLevel(req.Kind))
+			applyEnforcementToggle(r)
+			emitDenySideEffects(req, r, paramObj.ConstraintName, rhconfig.SideEffectConfig)
 			return r
 		}
 		if result.InScope {
+			detail = newVerificationDetail(result, nil)
 			if result.Verified {
 				allow = true
 				message = fmt.Sprintf("singed by a valid signer: %s", result.Signer)
-			} else {
-				allow = false
-				message = "Signature verification is required for this request, but no signature is found."
-				if result.Diff != nil && result.Diff.Size() > 0 {
-					message = fmt.Sprintf("Signature verification is required for this request, but failed to verify signature. diff found: %s", result.Diff.String())
-				} else if result.Signer != "" {
-					message = fmt.Sprintf("Signature verification is required for this request, but no signer config matches with this resource. This is signed by %s", result.Signer)
+				if requiredSignatures > 1 {
+					message = fmt.Sprintf("singed by %d valid signers: %s", validSignatures, result.Signer)
+				}
+				if staleMsg := getStaleSignatureMessage(result.SignedTime, rhconfig.MaxSignatureAge.Duration, rhconfig.ClockSkewAllowance.Duration, rhconfig.FailurePolicy, time.Now()); staleMsg != "" {
+					allow = false
+					message = staleMsg
+					reason = ReasonStaleSignature
 				}
+			} else {
+				allow, message = getUnverifiedResult(result, requiredSignatures, validSignatures, rhconfig.DefaultPosture)
 			}
 		} else {
 			allow = true
@@ -208,13 +271,23 @@ func RequestHandler(req admission.Request, paramObj *k8smnfconfig.ParameterObjec
 	r := &ResultFromRequestHandler{
 		Allow:   allow,
 		Message: message,
+		Reason:  reason,
+		Detail:  detail,
 	}
-
-	// generate events
-	if rhconfig.SideEffectConfig.CreateDenyEvent {
-		_ = createOrUpdateEvent(req, r, paramObj.ConstraintName)
+	// soft policy check
+	if r.Allow {
+		r.Warnings = getSoftPolicyWarnings(resource, rhconfig.WarningConfig)
+		r.Warnings = append(r.Warnings, keyWarnings...)
 	}
 
+	// enforcement level of the kind
+	applyEnforcementLevel(r, rhconfig.EnforcementConfig.GetLevel(req.Kind))
+	// enforcement toggle in IntegrityShield CR
+	applyEnforcementToggle(r)
+
+	// generate events and annotate namespace
+	emitDenySideEffects(req, r, paramObj.ConstraintName, rhconfig.SideEffectConfig)
+
 	// log
 	log.WithFields(log.Fields{
 		"namespace": req.Namespace,
@@ -229,15 +302,228 @@ func RequestHandler(req admission.Request, paramObj *k8smnfconfig.ParameterObjec
 }
 
 type ResultFromRequestHandler struct {
-	Allow   bool   `json:"allow"`
-	Message string `json:"message"`
-	Profile string `json:"profile,omitempty"`
+	Allow    bool     `json:"allow"`
+	Message  string   `json:"message"`
+	Reason   string   `json:"reason,omitempty"`
+	Profile  string   `json:"profile,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	// Detail is set only for requests which went through signature verification
+	Detail *VerificationDetail `json:"detail,omitempty"`
+}
+
+// decodeRequestObject unmarshals the requested object. A malformed object is returned as an error, not a panic.
+func decodeRequestObject(objectBytes []byte) (resource unstructured.Unstructured, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("panic while decoding the requested object: %v", r))
+		}
+	}()
+	if len(objectBytes) == 0 {
+		return resource, errors.New("the requested object is empty")
+	}
+	err = json.Unmarshal(objectBytes, &resource)
+	return resource, err
+}
+
+// newDecodeErrorResult decides the response for an object which cannot be decoded.
+// It is denied unless the failure policy is "Ignore", like failurePolicy of webhooks.
+func newDecodeErrorResult(err error, failurePolicy string) *ResultFromRequestHandler {
+	errMsg := "IntegrityShield failed to decide the response. Failed to Unmarshal a requested object: " + err.Error()
+	allow := false
+	if failurePolicy == k8smnfconfig.FailurePolicyIgnore {
+		allow = true
+		errMsg = "allowed by failure policy: " + errMsg
+	}
+	return &ResultFromRequestHandler{
+		Allow:   allow,
+		Message: errMsg,
+		Reason:  ReasonObjectDecodeError,
+	}
+}
+
+// emitDenySideEffects creates the deny event and annotates the namespace only if the request is finally denied,
+// i.e. after the enforcement level and the enforcement toggle are applied.
+func emitDenySideEffects(req admission.Request, r *ResultFromRequestHandler, constraintName string, seconfig k8smnfconfig.SideEffectConfig) {
+	if r.Allow {
+		return
+	}
+	if seconfig.CreateDenyEvent {
+		_ = createOrUpdateEvent(req, r, constraintName, seconfig)
+	}
+	if seconfig.AnnotateNamespaceOnDeny {
+		_ = annotateDeniedNamespace(req, r, seconfig)
+	}
+}
+
+// applyEnforcementLevel allows a denied request if the kind is at the audit level.
+func applyEnforcementLevel(r *ResultFromRequestHandler, level string) {
+	if r.Allow || level != k8smnfconfig.EnforcementLevelAudit {
+		return
+	}
+	r.Allow = true
+	r.Message = "allowed by audit level: " + r.Message
+}
+
+// getUnverifiedResult decides the response for a resource whose signature is not verified.
+// Under the "allow" posture, a resource without any signature is allowed, and only explicit verification failures are denied.
+func getUnverifiedResult(result *k8smanifest.VerifyResourceResult, requiredSignatures, validSignatures int, posture string) (bool, string) {
+	message := "Signature verification is required for this request, but no signature is found."
+	if requiredSignatures > 1 && validSignatures > 0 {
+		message = fmt.Sprintf("Signature verification is required for this request, but only %d valid signatures are found (required: %d). This is signed by %s", validSignatures, requiredSignatures, result.Signer)
+	} else if result.Diff != nil && result.Diff.Size() > 0 {
+		message = fmt.Sprintf("Signature verification is required for this request, but failed to verify signature. diff found: %s", result.Diff.String())
+	} else if result.Signer != "" {
+		message = fmt.Sprintf("Signature verification is required for this request, but no signer config matches with this resource. This is signed by %s", result.Signer)
+	} else if posture == k8smnfconfig.DefaultPostureAllow {
+		return true, "no signature is found, but allowed by default-allow posture."
+	}
+	return false, message
+}
+
+// getManifestRefFromAnnotation returns the manifest image ref in the annotation of the resource.
+// The annotation must be compared with the signed manifest, so it is an error if the annotation is in ignore fields.
+func getManifestRefFromAnnotation(resource unstructured.Unstructured, annotationKey string, ignoreFields k8smanifest.ObjectFieldBindingList) (string, error) {
+	if annotationKey == "" {
+		return "", nil
+	}
+	manifestRef, found := resource.GetAnnotations()[annotationKey]
+	if !found || manifestRef == "" {
+		return "", nil
+	}
+	annotationField := "metadata.annotations." + annotationKey
+	_, fields := ignoreFields.Match(resource)
+	for _, field := range fields {
+		if k8smnfutil.MatchPattern(field, annotationField) {
+			return "", errors.New(fmt.Sprintf("the annotation `%s` is not covered by the signature because it matches ignore field `%s`", annotationKey, field))
+		}
+	}
+	return manifestRef, nil
+}
+
+// getManifestRefFromLabels renders the manifest image ref from the labels of the resource with the template,
+// e.g. `registry.example.com/{{ label "app.kubernetes.io/part-of" }}-manifests:latest`.
+// The labels used in the template must be compared with the signed manifest, so it is an error if any of them is
+// in ignore fields. An empty string is returned if a label used in the template is not set.
+func getManifestRefFromLabels(resource unstructured.Unstructured, refTemplate string, ignoreFields k8smanifest.ObjectFieldBindingList) (string, error) {
+	if refTemplate == "" {
+		return "", nil
+	}
+	labels := resource.GetLabels()
+	usedLabels := []string{}
+	missing := false
+	funcMap := template.FuncMap{
+		"label": func(key string) string {
+			usedLabels = append(usedLabels, key)
+			if labels[key] == "" {
+				missing = true
+			}
+			return labels[key]
+		},
+	}
+	tmpl, err := template.New("manifestRef").Funcs(funcMap).Parse(refTemplate)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to parse the manifest ref template")
+	}
+	values := map[string]string{
+		"Namespace": resource.GetNamespace(),
+		"Kind":      resource.GetKind(),
+	}
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, values); err != nil {
+		return "", errors.Wrap(err, "failed to render the manifest ref template")
+	}
+	if missing {
+		log.Debugf("labels in the manifest ref template are not set; %v", usedLabels)
+		return "", nil
+	}
+	_, fields := ignoreFields.Match(resource)
+	for _, key := range usedLabels {
+		labelField := "metadata.labels." + key
+		for _, field := range fields {
+			if k8smnfutil.MatchPattern(field, labelField) {
+				return "", errors.New(fmt.Sprintf("the label `%s` is not covered by the signature because it matches ignore field `%s`", key, field))
+			}
+		}
+	}
+	return buf.String(), nil
+}
+
+// getOperationAction returns the action of the first operation rule which matches the request.
+// Requests which do not match any rule are verified if they are CREATE, UPDATE or DELETE, and allowed otherwise.
+func getOperationAction(req admission.Request, rules []k8smnfconfig.OperationRule) string {
+	for _, rule := range rules {
+		if rule.Match(string(req.Operation), req.SubResource) {
+			return rule.Action
+		}
+	}
+	switch req.Operation {
+	case v1.Create, v1.Update, v1.Delete:
+		return k8smnfconfig.OperationActionVerify
+	}
+	return k8smnfconfig.OperationActionAllow
+}
+
+// appendRequestIgnoreFields appends the ignore fields which depend on the request and the resource to the verify option.
+func appendRequestIgnoreFields(vo *k8smanifest.VerifyResourceOption, req admission.Request, resource unstructured.Unstructured, rhconfig *k8smnfconfig.RequestHandlerConfig) {
+	// expected deltas of the environment
+	vo.IgnoreFields = append(vo.IgnoreFields, getOverlayIgnoreFields(rhconfig.OverlayConfig, req.Namespace)...)
+	// immutable fields assigned by the API server are kept in resubmitted objects
+	if isUpdateRequest(req.AdmissionRequest.Operation) {
+		vo.IgnoreFields = append(vo.IgnoreFields, rhconfig.RequestFilterProfile.GetServerAssignedFields()...)
+	}
+	// name generated by the API server
+	if binding, ok := getGenerateNameIgnoreField(resource); ok {
+		vo.IgnoreFields = append(vo.IgnoreFields, binding)
+	}
+	// status of ConstraintTemplates and constraints written by Gatekeeper
+	if binding, ok := GetGatekeeperIgnoreField(resource); ok {
+		vo.IgnoreFields = append(vo.IgnoreFields, binding)
+	}
+}
+
+// reverifyResource verifies the resource again with the updated verify option.
+func reverifyResource(resource unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption, requiredSignatures int, timeout time.Duration) (*k8smanifest.VerifyResourceResult, int, error) {
+	if requiredSignatures > 1 {
+		return verifyResourceWithThreshold(resource, vo, requiredSignatures, timeout)
+	}
+	result, err := verifyResourceWithTimeout(resource, vo, timeout)
+	return result, 0, err
 }
 
 func isUpdateRequest(operation v1.Operation) bool {
 	return (operation == v1.Update)
 }
 
+// checkUpdateMutation is a fast path for UPDATE requests. If the differences between the old and new objects
+// are only in ignore fields or status, the request is allowed without signature verification.
+// It returns nil if the request needs to be verified, or if every UPDATE is verified by the config.
+func checkUpdateMutation(req admission.Request, resource unstructured.Unstructured, paramObj *k8smnfconfig.ParameterObject, rhconfig *k8smnfconfig.RequestHandlerConfig) *ResultFromRequestHandler {
+	if !isUpdateRequest(req.AdmissionRequest.Operation) || rhconfig.UpdateVerification == k8smnfconfig.UpdateVerificationAlways {
+		return nil
+	}
+	ignoreFields := getMatchedIgnoreFields(paramObj.IgnoreFields, rhconfig.RequestFilterProfile.IgnoreFields, resource)
+	_, stripFields := rhconfig.RequestFilterProfile.GetStripMetadataIgnoreFields().Match(resource)
+	ignoreFields = append(ignoreFields, stripFields...)
+	_, serverManagedFields := rhconfig.RequestFilterProfile.GetServerManagedIgnoreFields().Match(resource)
+	ignoreFields = append(ignoreFields, serverManagedFields...)
+	mutated, err := mutationCheck(req.AdmissionRequest.OldObject.Raw, req.AdmissionRequest.Object.Raw, ignoreFields)
+	if err != nil {
+		log.Errorf("failed to check mutation", err.Error())
+		errMsg := "IntegrityShield failed to decide the response. Failed to check mutation: " + err.Error()
+		return &ResultFromRequestHandler{
+			Allow:   false,
+			Message: errMsg,
+		}
+	}
+	if !mutated {
+		return &ResultFromRequestHandler{
+			Allow:   true,
+			Message: "no mutation found",
+		}
+	}
+	return nil
+}
+
 func getMatchedIgnoreFields(pi, ci k8smanifest.ObjectFieldBindingList, resource unstructured.Unstructured) []string {
 	var allIgnoreFields []string
 	_, fields := pi.Match(resource)
@@ -285,6 +571,7 @@ func mutationCheck(rawOldObject, rawObject []byte, IgnoreFields []string) (bool,
 	unfiltered := &mapnode.DiffResult{}
 	if dr != nil && dr.Size() > 0 {
 		_, unfiltered, _ = dr.Filter(IgnoreFields)
+		recordIgnoreFieldMatches(dr, IgnoreFields)
 	}
 	if unfiltered.Size() == 0 {
 		return false, nil
@@ -292,7 +579,10 @@ func mutationCheck(rawOldObject, rawObject []byte, IgnoreFields []string) (bool,
 	return true, nil
 }
 
-func setVerifyOption(paramObj *k8smnfconfig.ParameterObject, config *k8smnfconfig.RequestHandlerConfig, signatureAnnotationType string) *k8smanifest.VerifyResourceOption {
+// loadKeySecret can be replaced in tests
+var loadKeySecret = k8smnfconfig.LoadKeySecret
+
+func setVerifyOption(paramObj *k8smnfconfig.ParameterObject, config *k8smnfconfig.RequestHandlerConfig, signatureAnnotationType, reqNamespace string) *k8smanifest.VerifyResourceOption {
 	// get verifyOption and imageRef from Parameter
 	vo := &paramObj.VerifyResourceOption
 	vo.CheckDryRunForApply = true
@@ -306,29 +596,33 @@ func setVerifyOption(paramObj *k8smnfconfig.ParameterObject, config *k8smnfconfi
 		vo.AnnotationConfig.AnnotationKeyDomain = AnnotationKeyDomain
 	}
 	// prepare local key for verifyResource
-	if len(paramObj.KeyConfigs) != 0 {
-		keyPathList := []string{}
+	// keys for the namespace of the request replace the shared keys, so that a key of another team is never accepted
+	keyPathList, found := config.GetNamespacedKeyPathList(reqNamespace)
+	if !found {
+		keyPathList = []string{}
 		for _, keyconfig := range paramObj.KeyConfigs {
 			if keyconfig.KeySecretName != "" {
-				keyPath, err := k8smnfconfig.LoadKeySecret(keyconfig.KeySecretNamespace, keyconfig.KeySecretName)
+				keyPath, err := loadKeySecret(keyconfig.KeySecretNamespace, keyconfig.KeySecretName)
 				if err != nil {
-					log.Errorf("failed to load key secret", err.Error())
+					log.Errorf("failed to load key secret; %s", err.Error())
+					continue
 				}
 				keyPathList = append(keyPathList, keyPath)
 			}
 		}
-		keyPathString := strings.Join(keyPathList, ",")
-		if keyPathString != "" {
-			vo.KeyPath = keyPathString
-		}
+		keyPathList = append(keyPathList, config.KeyPathList...)
 	}
-	// merge params in request handler config
-	if len(config.RequestFilterProfile.IgnoreFields) == 0 {
-		return vo
+	keyPathString := strings.Join(keyPathList, ",")
+	if keyPathString != "" {
+		vo.KeyPath = keyPathString
 	}
+	// merge params in request handler config
 	fields := k8smanifest.ObjectFieldBindingList{}
 	fields = append(fields, vo.IgnoreFields...)
 	fields = append(fields, config.RequestFilterProfile.IgnoreFields...)
+	fields = append(fields, config.RequestFilterProfile.GetStripMetadataIgnoreFields()...)
+	// fields populated by the API server, e.g. those recorded by server-side apply, are never part of signed manifests
+	fields = append(fields, config.RequestFilterProfile.GetServerManagedIgnoreFields()...)
 	vo.IgnoreFields = fields
 	return vo
 }
@@ -370,6 +664,12 @@ func LoadRequestHandlerConfig() (*k8smnfconfig.RequestHandlerConfig, error) {
 	if err != nil {
 		return sc, errors.Wrap(err, fmt.Sprintf("failed to unmarshal config.yaml into %T", sc))
 	}
+	// fragments of the filter profile
+	fragments, fragmentBytes := loadRequestFilterProfileFragments(clientset, namespace)
+	if sc != nil && len(fragments) != 0 {
+		sc.RequestFilterProfile = sc.RequestFilterProfile.Merge(fragments...)
+	}
+	_ = recordConfigLoad(getConfigHash(append([]byte(cfgBytes), fragmentBytes...)), time.Now())
 	return sc, nil
 }
 
@@ -385,7 +685,35 @@ func skipObjectsMatch(l k8smanifest.ObjectReferenceList, obj unstructured.Unstru
 	return false
 }
 
-func createOrUpdateEvent(req admission.Request, ar *ResultFromRequestHandler, constraintName string) error {
+// getOwnerResource can be replaced in tests
+var getOwnerResource = kubeutil.GetResource
+
+// isOwnedByController returns true if the request is sent by one of the controllers, the object has an ownerReference
+// to a controller, the owner exists with the same UID and the owner is in scope, so that the owner is verified instead.
+// An ownerReference can be set by anyone who can read the owner, so it does not skip the verification of requests by other users.
+func isOwnedByController(obj unstructured.Unstructured, inScopeObjects k8smanifest.ObjectReferenceList, username string, controllers []string) bool {
+	if !k8smnfutil.MatchWithPatternArray(username, controllers) {
+		return false
+	}
+	for _, ref := range obj.GetOwnerReferences() {
+		if ref.Controller == nil || !*ref.Controller {
+			continue
+		}
+		owner, err := getOwnerResource(ref.APIVersion, ref.Kind, obj.GetNamespace(), ref.Name)
+		if err != nil {
+			log.Debugf("failed to get the owner %s `%s` of %s `%s`; %s", ref.Kind, ref.Name, obj.GetKind(), obj.GetName(), err.Error())
+			return false
+		}
+		if owner == nil || owner.GetUID() != ref.UID {
+			log.Debugf("the owner %s `%s` of %s `%s` is not found with uid `%s`", ref.Kind, ref.Name, obj.GetKind(), obj.GetName(), ref.UID)
+			return false
+		}
+		return inScopeObjects.Match(*owner)
+	}
+	return false
+}
+
+func createOrUpdateEvent(req admission.Request, ar *ResultFromRequestHandler, constraintName string, seconfig k8smnfconfig.SideEffectConfig) error {
 	// no event is generated for allowed request
 	if ar.Allow {
 		return nil
@@ -400,6 +728,65 @@ func createOrUpdateEvent(req admission.Request, ar *ResultFromRequestHandler, co
 		return err
 	}
 
+	now := time.Now()
+	evt := newDenyEvent(req, seconfig, now)
+	evtName := evt.Name
+	evtNamespace := evt.Namespace
+	isExistingEvent := false
+	current, getErr := client.CoreV1().Events(evtNamespace).Get(context.Background(), evtName, metav1.GetOptions{})
+	if current != nil && getErr == nil {
+		isExistingEvent = true
+		evt = current
+	}
+
+	tmpMessage := "[" + constraintName + "]" + ar.Message
+	// tmpMessage := ar.Message
+	// Event.Message can have 1024 chars at most
+	if len(tmpMessage) > 1024 {
+		tmpMessage = tmpMessage[:950] + " ... Trimmed. `Event.Message` can have 1024 chars at maximum."
+	}
+	updateDenyEvent(evt, tmpMessage, isExistingEvent, now, seconfig.DenyEventAggregationWindow.Duration)
+
+	if isExistingEvent {
+		_, err = client.CoreV1().Events(evtNamespace).Update(context.Background(), evt, metav1.UpdateOptions{})
+	} else {
+		_, err = client.CoreV1().Events(evtNamespace).Create(context.Background(), evt, metav1.CreateOptions{})
+	}
+	if err != nil {
+		log.Errorf("failed to generate deny event; %s", err.Error())
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"namespace": req.Namespace,
+		"name":      req.Name,
+		"kind":      req.Kind.Kind,
+		"operation": req.Operation,
+	}).Debug("Deny event is generated:", evtName)
+
+	return nil
+}
+
+// updateDenyEvent sets the message and the count of a deny event.
+// An identical denial is aggregated into the existing event unless the last one is older than the window.
+func updateDenyEvent(evt *corev1.Event, message string, isExistingEvent bool, now time.Time, window time.Duration) {
+	aggregate := isExistingEvent && evt.Message == message
+	if aggregate && window > 0 && now.Sub(evt.LastTimestamp.Time) > window {
+		aggregate = false
+	}
+	if !aggregate {
+		evt.Count = 0
+		evt.FirstTimestamp = metav1.NewTime(now)
+	}
+	evt.Message = message
+	evt.Count = evt.Count + 1
+	evt.EventTime = metav1.NewMicroTime(now)
+	evt.LastTimestamp = metav1.NewTime(now)
+}
+
+// newDenyEvent returns a deny event for the request. The namespace, the type and the reason of
+// the event can be changed by the side effect config.
+func newDenyEvent(req admission.Request, seconfig k8smnfconfig.SideEffectConfig, now time.Time) *corev1.Event {
 	namespace := os.Getenv("POD_NAMESPACE")
 	if namespace == "" {
 		namespace = defaultPodNamespace
@@ -409,16 +796,30 @@ func createOrUpdateEvent(req admission.Request, ar *ResultFromRequestHandler, co
 	if evtNamespace == "" {
 		evtNamespace = namespace
 	}
+	evtName := fmt.Sprintf("ishield-deny-%s-%s-%s", strings.ToLower(string(req.Operation)), strings.ToLower(req.Kind.Kind), req.Name)
+	if seconfig.DenyEventNamespace != "" {
+		// events of all namespaces are gathered in one namespace, so the name includes the namespace of the resource
+		evtNamespace = seconfig.DenyEventNamespace
+		if req.Namespace != "" {
+			evtName = fmt.Sprintf("ishield-deny-%s-%s-%s-%s", strings.ToLower(string(req.Operation)), strings.ToLower(req.Kind.Kind), req.Namespace, req.Name)
+		}
+	}
 	involvedObject := corev1.ObjectReference{
 		Namespace:  req.Namespace,
 		APIVersion: gv.String(),
 		Kind:       req.Kind.Kind,
 		Name:       req.Name,
 	}
-	evtName := fmt.Sprintf("ishield-deny-%s-%s-%s", strings.ToLower(string(req.Operation)), strings.ToLower(req.Kind.Kind), req.Name)
 	sourceName := "IntegrityShield"
+	evtType := sourceName
+	if seconfig.DenyEventType != "" {
+		evtType = seconfig.DenyEventType
+	}
+	reason := "Deny"
+	if seconfig.DenyEventReason != "" {
+		reason = renderDenyEventReason(seconfig.DenyEventReason, req)
+	}
 
-	now := time.Now()
 	evt := &corev1.Event{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      evtName,
@@ -429,48 +830,35 @@ func createOrUpdateEvent(req admission.Request, ar *ResultFromRequestHandler, co
 			},
 		},
 		InvolvedObject:      involvedObject,
-		Type:                sourceName,
+		Type:                evtType,
 		Source:              corev1.EventSource{Component: sourceName},
 		ReportingController: sourceName,
 		ReportingInstance:   evtName,
 		Action:              evtName,
-		Reason:              "Deny",
+		Reason:              reason,
 		FirstTimestamp:      metav1.NewTime(now),
 	}
-	isExistingEvent := false
-	current, getErr := client.CoreV1().Events(evtNamespace).Get(context.Background(), evtName, metav1.GetOptions{})
-	if current != nil && getErr == nil {
-		isExistingEvent = true
-		evt = current
-	}
+	return evt
+}
 
-	tmpMessage := "[" + constraintName + "]" + ar.Message
-	// tmpMessage := ar.Message
-	// Event.Message can have 1024 chars at most
-	if len(tmpMessage) > 1024 {
-		tmpMessage = tmpMessage[:950] + " ... Trimmed. `Event.Message` can have 1024 chars at maximum."
+// renderDenyEventReason renders the reason template with the fields of the request,
+// e.g. `Deny{{.Kind}}` or `{{.Operation}}Denied`.
+func renderDenyEventReason(reasonTemplate string, req admission.Request) string {
+	tmpl, err := template.New("reason").Parse(reasonTemplate)
+	if err != nil {
+		log.Errorf("failed to parse the reason template of deny event; %s", err.Error())
+		return "Deny"
 	}
-	evt.Message = tmpMessage
-	evt.Count = evt.Count + 1
-	evt.EventTime = metav1.NewMicroTime(now)
-	evt.LastTimestamp = metav1.NewTime(now)
-
-	if isExistingEvent {
-		_, err = client.CoreV1().Events(evtNamespace).Update(context.Background(), evt, metav1.UpdateOptions{})
-	} else {
-		_, err = client.CoreV1().Events(evtNamespace).Create(context.Background(), evt, metav1.CreateOptions{})
+	values := map[string]string{
+		"Namespace": req.Namespace,
+		"Name":      req.Name,
+		"Kind":      req.Kind.Kind,
+		"Operation": string(req.Operation),
 	}
-	if err != nil {
-		log.Errorf("failed to generate deny event; %s", err.Error())
-		return err
+	buf := new(bytes.Buffer)
+	if err := tmpl.Execute(buf, values); err != nil {
+		log.Errorf("failed to render the reason of deny event; %s", err.Error())
+		return "Deny"
 	}
-
-	log.WithFields(log.Fields{
-		"namespace": req.Namespace,
-		"name":      req.Name,
-		"kind":      req.Kind.Kind,
-		"operation": req.Operation,
-	}).Debug("Deny event is generated:", evtName)
-
-	return nil
+	return buf.String()
 }
@@ -0,0 +1,645 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/pkg/errors"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+	admv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+const testSSAConfigMap = `{
+	"apiVersion": "v1",
+	"kind": "ConfigMap",
+	"metadata": {
+		"name": "sample-cm",
+		"namespace": "sample-ns",
+		"managedFields": [
+			{
+				"apiVersion": "v1",
+				"fieldsType": "FieldsV1",
+				"fieldsV1": {"f:data": {"f:key1": {}}},
+				"manager": "kubectl",
+				"operation": "Apply"
+			}
+		]
+	},
+	"data": {
+		"key1": "val1"
+	}
+}`
+
+func loadTestObject(t *testing.T, objStr string) unstructured.Unstructured {
+	var obj unstructured.Unstructured
+	if err := json.Unmarshal([]byte(objStr), &obj); err != nil {
+		t.Fatalf("failed to unmarshal a test object: %s", err.Error())
+	}
+	return obj
+}
+
+// getUnignoredDiff returns the diff between the resource and the signed manifest which is not filtered by
+// the ignore fields of the option. VerifyResource compares the manifest in a signature in the same way.
+func getUnignoredDiff(t *testing.T, resource, signed unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption) *mapnode.DiffResult {
+	signedBytes, _ := json.Marshal(signed.Object)
+	signedNode, err := mapnode.NewFromBytes(signedBytes)
+	if err != nil {
+		t.Fatalf("failed to load the signed manifest: %s", err.Error())
+	}
+	resourceBytes, _ := json.Marshal(resource.Object)
+	resourceNode, err := mapnode.NewFromBytes(resourceBytes)
+	if err != nil {
+		t.Fatalf("failed to load the resource: %s", err.Error())
+	}
+	diff := resourceNode.Diff(signedNode)
+	if diff == nil || diff.Size() == 0 {
+		return nil
+	}
+	_, fields := vo.IgnoreFields.Match(resource)
+	_, unfiltered, _ := diff.Filter(fields)
+	return unfiltered
+}
+
+func TestSetVerifyOptionIgnoresServerSideApplyFields(t *testing.T) {
+	obj := loadTestObject(t, testSSAConfigMap)
+	paramObj := &k8smnfconfig.ParameterObject{}
+	rhconfig := &k8smnfconfig.RequestHandlerConfig{}
+	vo := setVerifyOption(paramObj, rhconfig, "", "sample-ns")
+
+	_, fields := vo.IgnoreFields.Match(obj)
+	found := false
+	for _, f := range fields {
+		if f == "metadata.managedFields.*" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("managedFields should be ignored for server-side applied objects; ignoreFields: %v", fields)
+	}
+
+	// managedFields are ignored even if the server-managed fields are disabled
+	rhconfig.RequestFilterProfile.ServerManagedFields = []string{}
+	vo = setVerifyOption(&k8smnfconfig.ParameterObject{}, rhconfig, "", "sample-ns")
+	_, fields = vo.IgnoreFields.Match(obj)
+	found = false
+	for _, f := range fields {
+		if f == "metadata.managedFields.*" {
+			found = true
+		}
+		if f == "metadata.resourceVersion" {
+			t.Errorf("disabled server-managed fields should not be ignored; ignoreFields: %v", fields)
+		}
+	}
+	if !found {
+		t.Errorf("managedFields should be ignored with `serverManagedFields: []`; ignoreFields: %v", fields)
+	}
+}
+
+func TestServerSideAppliedObjectIgnoreFields(t *testing.T) {
+	signed := loadTestObject(t, `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "sample-cm", "namespace": "sample-ns"}, "data": {"key1": "val1"}}`)
+	req := admission.Request{AdmissionRequest: admv1.AdmissionRequest{Namespace: "sample-ns", Name: "sample-cm", Operation: admv1.Create}}
+	newVerifyOption := func(obj unstructured.Unstructured, rhconfig *k8smnfconfig.RequestHandlerConfig) *k8smanifest.VerifyResourceOption {
+		vo := setVerifyOption(&k8smnfconfig.ParameterObject{}, rhconfig, "", "sample-ns")
+		appendRequestIgnoreFields(vo, req, obj, rhconfig)
+		return vo
+	}
+
+	// the object applied with server-side apply has managedFields and the other fields populated by the API server
+	obj := loadTestObject(t, testSSAConfigMap)
+	obj.SetResourceVersion("12345")
+	obj.SetUID("0c1f3a4e-2b7d-4e8a-9f6c-5d3b2a1e0f9c")
+	rhconfig := &k8smnfconfig.RequestHandlerConfig{}
+	if diff := getUnignoredDiff(t, obj, signed, newVerifyOption(obj, rhconfig)); diff != nil && diff.Size() > 0 {
+		t.Errorf("server-side applied object should be verified with the signed manifest; diff: %s", diff.String())
+	}
+
+	// managedFields alone do not fail the verification even if the server-managed fields are disabled
+	obj = loadTestObject(t, testSSAConfigMap)
+	rhconfig.RequestFilterProfile.ServerManagedFields = []string{}
+	if diff := getUnignoredDiff(t, obj, signed, newVerifyOption(obj, rhconfig)); diff != nil && diff.Size() > 0 {
+		t.Errorf("server-side applied object should be verified with `serverManagedFields: []`; diff: %s", diff.String())
+	}
+
+	// modified data is still detected
+	obj = loadTestObject(t, testSSAConfigMap)
+	_ = unstructured.SetNestedField(obj.Object, "val2", "data", "key1")
+	if diff := getUnignoredDiff(t, obj, signed, newVerifyOption(obj, rhconfig)); diff == nil || diff.Size() == 0 {
+		t.Error("server-side applied object with modified data should not be verified")
+	}
+}
+
+func TestSetVerifyOptionWithNamespacedKeys(t *testing.T) {
+	rhconfig := &k8smnfconfig.RequestHandlerConfig{
+		KeyPathList: []string{"/keys/global.pub"},
+		NamespacedKeyPathList: []k8smnfconfig.NamespacedKeyPaths{
+			{Namespaces: []string{"team-a"}, KeyPathList: []string{"/keys/team-a.pub"}},
+			{Namespaces: []string{"team-b"}, KeyPathList: []string{"/keys/team-b.pub"}},
+		},
+	}
+	orgLoadKeySecret := loadKeySecret
+	defer func() { loadKeySecret = orgLoadKeySecret }()
+	loadKeySecret = func(namespace, name string) (string, error) {
+		return "/tmp/" + namespace + "/" + name + "/key.pub", nil
+	}
+	newParamObj := func() *k8smnfconfig.ParameterObject {
+		return &k8smnfconfig.ParameterObject{KeyConfigs: []k8smnfconfig.KeyConfig{{KeySecretNamespace: "ishield", KeySecretName: "shared-key"}}}
+	}
+
+	// a resource in team-a namespace is verified only with team-a key; the shared keys of the constraint are not used
+	vo := setVerifyOption(newParamObj(), rhconfig, "", "team-a")
+	if vo.KeyPath != "/keys/team-a.pub" {
+		t.Errorf("unexpected keys for team-a: %s", vo.KeyPath)
+	}
+
+	// fall back to the keys of the constraint and the global key list
+	vo = setVerifyOption(newParamObj(), rhconfig, "", "team-c")
+	if vo.KeyPath != "/tmp/ishield/shared-key/key.pub,/keys/global.pub" {
+		t.Errorf("unexpected keys for team-c: %s", vo.KeyPath)
+	}
+}
+
+func TestCrossTeamSignatureIsDenied(t *testing.T) {
+	rhconfig := &k8smnfconfig.RequestHandlerConfig{
+		NamespacedKeyPathList: []k8smnfconfig.NamespacedKeyPaths{
+			{Namespaces: []string{"team-a"}, KeyPathList: []string{"/keys/team-a.pub"}},
+			{Namespaces: []string{"team-b"}, KeyPathList: []string{"/keys/team-b.pub"}},
+		},
+	}
+	orgLoadKeySecret := loadKeySecret
+	orgVerifyFunc := verifyResourceFunc
+	defer func() {
+		loadKeySecret = orgLoadKeySecret
+		verifyResourceFunc = orgVerifyFunc
+	}()
+	loadKeySecret = func(namespace, name string) (string, error) {
+		return "/keys/team-b.pub", nil
+	}
+	// the resource is signed by team-b key
+	verifyResourceFunc = func(obj unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption) (*k8smanifest.VerifyResourceResult, error) {
+		for _, keyPath := range strings.Split(vo.KeyPath, ",") {
+			if keyPath == "/keys/team-b.pub" {
+				return &k8smanifest.VerifyResourceResult{InScope: true, Verified: true, Signer: "team-b"}, nil
+			}
+		}
+		return &k8smanifest.VerifyResourceResult{InScope: true, Verified: false}, nil
+	}
+
+	obj := loadTestObject(t, `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"name": "sample-cm", "namespace": "team-a"}}`)
+	// team-b key is also a shared key of the constraint
+	paramObj := &k8smnfconfig.ParameterObject{KeyConfigs: []k8smnfconfig.KeyConfig{{KeySecretNamespace: "ishield", KeySecretName: "team-b-key"}}}
+	vo := setVerifyOption(paramObj, rhconfig, "", "team-a")
+	result, err := verifyResourceWithTimeout(obj, vo, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if result.Verified {
+		t.Errorf("a resource in team-a signed by team-b key should be denied; keys: %s", vo.KeyPath)
+	}
+
+	// the same signature is accepted in team-b
+	obj.SetNamespace("team-b")
+	vo = setVerifyOption(&k8smnfconfig.ParameterObject{}, rhconfig, "", "team-b")
+	result, err = verifyResourceWithTimeout(obj, vo, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if !result.Verified {
+		t.Errorf("a resource in team-b signed by team-b key should be verified; keys: %s", vo.KeyPath)
+	}
+}
+
+func TestUpdateDenyEventAggregatesIdenticalDenials(t *testing.T) {
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	window := 1 * time.Minute
+	msg := "[configmap-constraint]no signature found"
+
+	evt := &corev1.Event{}
+	updateDenyEvent(evt, msg, false, now, window)
+	for i := 1; i < 5; i++ {
+		updateDenyEvent(evt, msg, true, now.Add(time.Duration(i)*time.Second), window)
+	}
+	if evt.Count != 5 {
+		t.Errorf("5 identical denials should be aggregated into one event; count: %d", evt.Count)
+	}
+	if !evt.FirstTimestamp.Time.Equal(now) {
+		t.Errorf("first timestamp should not change while aggregating: %s", evt.FirstTimestamp.String())
+	}
+
+	// a different denial starts a new series
+	updateDenyEvent(evt, "[configmap-constraint]diff found", true, now.Add(10*time.Second), window)
+	if evt.Count != 1 {
+		t.Errorf("a different denial should not be aggregated; count: %d", evt.Count)
+	}
+
+	// an identical denial after the window starts a new series
+	updateDenyEvent(evt, "[configmap-constraint]diff found", true, now.Add(5*time.Minute), window)
+	if evt.Count != 1 {
+		t.Errorf("a denial after the window should not be aggregated; count: %d", evt.Count)
+	}
+}
+
+func TestNewDenyEventWithCentralNamespace(t *testing.T) {
+	req := admission.Request{
+		AdmissionRequest: admv1.AdmissionRequest{
+			Kind:      metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"},
+			Namespace: "sample-ns",
+			Name:      "sample-cm",
+			Operation: admv1.Create,
+		},
+	}
+	now := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+
+	// default: the event lands in the namespace of the resource
+	evt := newDenyEvent(req, k8smnfconfig.SideEffectConfig{}, now)
+	if evt.Namespace != "sample-ns" || evt.Reason != "Deny" || evt.Type != "IntegrityShield" {
+		t.Errorf("unexpected default event; namespace: %s, reason: %s, type: %s", evt.Namespace, evt.Reason, evt.Type)
+	}
+
+	seconfig := k8smnfconfig.SideEffectConfig{
+		DenyEventNamespace: "ishield-events",
+		DenyEventType:      corev1.EventTypeWarning,
+		DenyEventReason:    "{{.Operation}}{{.Kind}}Denied",
+	}
+	evt = newDenyEvent(req, seconfig, now)
+	if evt.Namespace != "ishield-events" {
+		t.Errorf("event should land in the configured namespace: %s", evt.Namespace)
+	}
+	if evt.Reason != "CREATEConfigMapDenied" {
+		t.Errorf("unexpected reason: %s", evt.Reason)
+	}
+	if evt.Type != corev1.EventTypeWarning {
+		t.Errorf("unexpected type: %s", evt.Type)
+	}
+	if evt.Name != "ishield-deny-create-configmap-sample-ns-sample-cm" {
+		t.Errorf("event name should include the namespace of the resource: %s", evt.Name)
+	}
+	if evt.InvolvedObject.Namespace != "sample-ns" {
+		t.Errorf("involved object should keep the namespace of the resource: %s", evt.InvolvedObject.Namespace)
+	}
+}
+
+func TestApplyEnforcementLevel(t *testing.T) {
+	config := k8smnfconfig.EnforcementConfig{
+		Levels: []k8smnfconfig.KindEnforcementLevel{
+			{
+				Kinds: []metav1.GroupVersionKind{{Group: "apps", Kind: "Deployment"}, {Group: "apps", Kind: "DaemonSet"}},
+				Level: k8smnfconfig.EnforcementLevelEnforce,
+			},
+			{
+				Kinds: []metav1.GroupVersionKind{{Kind: "ConfigMap"}},
+				Level: k8smnfconfig.EnforcementLevelAudit,
+			},
+		},
+		DefaultLevel: k8smnfconfig.EnforcementLevelAudit,
+	}
+
+	deploy := metav1.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}
+	r := &ResultFromRequestHandler{Allow: false, Message: "no signature found"}
+	applyEnforcementLevel(r, config.GetLevel(deploy))
+	if r.Allow {
+		t.Error("Deployment should be enforced")
+	}
+
+	cm := metav1.GroupVersionKind{Version: "v1", Kind: "ConfigMap"}
+	r = &ResultFromRequestHandler{Allow: false, Message: "no signature found"}
+	applyEnforcementLevel(r, config.GetLevel(cm))
+	if !r.Allow || r.Message != "allowed by audit level: no signature found" {
+		t.Errorf("ConfigMap should be audited: %v", r)
+	}
+
+	if level := (k8smnfconfig.EnforcementConfig{}).GetLevel(cm); level != k8smnfconfig.EnforcementLevelEnforce {
+		t.Errorf("default level should be enforce: %s", level)
+	}
+}
+
+func TestStripMetadataKeys(t *testing.T) {
+	profile := k8smnfconfig.RequestFilterProfile{
+		StripMetadataKeys: []string{"pod-template-hash", "argocd.argoproj.io/*"},
+	}
+	oldObj := loadTestObject(t, testSSAConfigMap)
+	newObj := oldObj.DeepCopy()
+	newObj.SetLabels(map[string]string{"pod-template-hash": "5d4f8c7b9"})
+	newObj.SetAnnotations(map[string]string{"argocd.argoproj.io/sync-wave": "1"})
+	oldBytes, _ := json.Marshal(oldObj.Object)
+	newBytes, _ := json.Marshal(newObj.Object)
+
+	_, fields := profile.GetStripMetadataIgnoreFields().Match(*newObj)
+	mutated, err := mutationCheck(oldBytes, newBytes, fields)
+	if err != nil {
+		t.Errorf("failed to compare objects: %s", err.Error())
+		return
+	}
+	if mutated {
+		t.Errorf("stripped label and annotation should be ignored; fields: %v", fields)
+	}
+
+	// keys not in the list are still compared
+	newObj.SetAnnotations(map[string]string{"example.com/owner": "team-a"})
+	newBytes, _ = json.Marshal(newObj.Object)
+	mutated, _ = mutationCheck(oldBytes, newBytes, fields)
+	if !mutated {
+		t.Error("annotation not in the strip list should be compared")
+	}
+}
+
+func TestDecodeErrorResult(t *testing.T) {
+	for _, raw := range []string{`{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {`, `[]`, ``} {
+		_, err := decodeRequestObject([]byte(raw))
+		if err == nil {
+			t.Errorf("malformed object should not be decoded: %s", raw)
+			continue
+		}
+		r := newDecodeErrorResult(err, "")
+		if r.Allow || r.Reason != ReasonObjectDecodeError {
+			t.Errorf("malformed object should be denied with the reason code: %v", r)
+		}
+		r = newDecodeErrorResult(err, k8smnfconfig.FailurePolicyIgnore)
+		if !r.Allow || r.Reason != ReasonObjectDecodeError {
+			t.Errorf("malformed object should be allowed by the failure policy: %v", r)
+		}
+	}
+
+	if _, err := decodeRequestObject([]byte(testSSAConfigMap)); err != nil {
+		t.Errorf("failed to decode a valid object: %s", err.Error())
+	}
+}
+
+func TestGetOperationAction(t *testing.T) {
+	exec := admission.Request{
+		AdmissionRequest: admv1.AdmissionRequest{
+			Kind:        metav1.GroupVersionKind{Version: "v1", Kind: "PodExecOptions"},
+			Operation:   admv1.Connect,
+			SubResource: "exec",
+		},
+	}
+	if action := getOperationAction(exec, nil); action != k8smnfconfig.OperationActionAllow {
+		t.Errorf("CONNECT should be allowed by default: %s", action)
+	}
+
+	rules := []k8smnfconfig.OperationRule{
+		{Operations: []string{"CONNECT"}, SubResources: []string{"exec", "attach"}, Action: k8smnfconfig.OperationActionDeny},
+	}
+	if action := getOperationAction(exec, rules); action != k8smnfconfig.OperationActionDeny {
+		t.Errorf("CONNECT to exec should be denied by the rule: %s", action)
+	}
+	exec.SubResource = "portforward"
+	if action := getOperationAction(exec, rules); action != k8smnfconfig.OperationActionAllow {
+		t.Errorf("CONNECT to portforward should be allowed: %s", action)
+	}
+
+	create := admission.Request{AdmissionRequest: admv1.AdmissionRequest{Operation: admv1.Create}}
+	if action := getOperationAction(create, rules); action != k8smnfconfig.OperationActionVerify {
+		t.Errorf("CREATE should be verified: %s", action)
+	}
+}
+
+func TestGetManifestRefFromAnnotation(t *testing.T) {
+	annotationKey := "integrityshield.io/manifestRef"
+	obj := loadTestObject(t, testSSAConfigMap)
+	obj.SetAnnotations(map[string]string{annotationKey: "sample-registry/sample-cm:0.1"})
+
+	ref, err := getManifestRefFromAnnotation(obj, annotationKey, nil)
+	if err != nil || ref != "sample-registry/sample-cm:0.1" {
+		t.Errorf("manifest ref should be read from the annotation; ref: %s, err: %v", ref, err)
+	}
+
+	// the annotation cannot be trusted if it is ignored in comparison
+	ignoreFields := k8smanifest.ObjectFieldBindingList{
+		{Fields: []string{"metadata.annotations.*"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap"}}},
+	}
+	if _, err := getManifestRefFromAnnotation(obj, annotationKey, ignoreFields); err == nil {
+		t.Error("ignored annotation should not be used as manifest ref")
+	}
+
+	// no annotation
+	ref, err = getManifestRefFromAnnotation(loadTestObject(t, testSSAConfigMap), annotationKey, nil)
+	if err != nil || ref != "" {
+		t.Errorf("unexpected manifest ref: %s", ref)
+	}
+}
+
+func TestGetUnverifiedResultWithPosture(t *testing.T) {
+	unsigned := &k8smanifest.VerifyResourceResult{InScope: true}
+	if allow, _ := getUnverifiedResult(unsigned, 0, 0, ""); allow {
+		t.Error("unsigned resource should be denied under default-deny posture")
+	}
+	if allow, _ := getUnverifiedResult(unsigned, 0, 0, k8smnfconfig.DefaultPostureDeny); allow {
+		t.Error("unsigned resource should be denied under default-deny posture")
+	}
+	if allow, _ := getUnverifiedResult(unsigned, 0, 0, k8smnfconfig.DefaultPostureAllow); !allow {
+		t.Error("unsigned resource should be allowed under default-allow posture")
+	}
+
+	// explicit verification failure is denied under both postures
+	untrusted := &k8smanifest.VerifyResourceResult{InScope: true, Signer: "unknown@signer.com"}
+	if allow, _ := getUnverifiedResult(untrusted, 0, 0, k8smnfconfig.DefaultPostureAllow); allow {
+		t.Error("resource signed by an untrusted signer should be denied")
+	}
+}
+
+func TestCheckUpdateMutation(t *testing.T) {
+	oldObj := loadTestObject(t, testSSAConfigMap)
+	oldBytes, _ := json.Marshal(oldObj.Object)
+	newUpdateRequest := func(newObj *unstructured.Unstructured) admission.Request {
+		newBytes, _ := json.Marshal(newObj.Object)
+		return admission.Request{
+			AdmissionRequest: admv1.AdmissionRequest{
+				Operation: admv1.Update,
+				OldObject: runtime.RawExtension{Raw: oldBytes},
+				Object:    runtime.RawExtension{Raw: newBytes},
+			},
+		}
+	}
+	paramObj := &k8smnfconfig.ParameterObject{}
+	paramObj.IgnoreFields = k8smanifest.ObjectFieldBindingList{
+		{Fields: []string{"data.key2"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap"}}},
+	}
+	rhconfig := &k8smnfconfig.RequestHandlerConfig{}
+
+	// change only in an ignored field
+	newObj := oldObj.DeepCopy()
+	_ = unstructured.SetNestedField(newObj.Object, "val2", "data", "key2")
+	r := checkUpdateMutation(newUpdateRequest(newObj), *newObj, paramObj, rhconfig)
+	if r == nil || !r.Allow {
+		t.Errorf("update only in an ignored field should be allowed without verification: %v", r)
+	}
+
+	// change only in status
+	newObj = oldObj.DeepCopy()
+	_ = unstructured.SetNestedField(newObj.Object, "Ready", "status", "phase")
+	r = checkUpdateMutation(newUpdateRequest(newObj), *newObj, paramObj, rhconfig)
+	if r == nil || !r.Allow {
+		t.Errorf("update only in status should be allowed without verification: %v", r)
+	}
+
+	// change in a field which is not ignored
+	newObj = oldObj.DeepCopy()
+	_ = unstructured.SetNestedField(newObj.Object, "changed", "data", "key1")
+	if r = checkUpdateMutation(newUpdateRequest(newObj), *newObj, paramObj, rhconfig); r != nil {
+		t.Errorf("update in a field which is not ignored should be verified: %v", r)
+	}
+}
+
+func TestIsOwnedByController(t *testing.T) {
+	owner := loadTestObject(t, `{"apiVersion": "apps/v1", "kind": "ReplicaSet", "metadata": {"name": "sample-rs", "namespace": "sample-ns", "uid": "6b5a3a1e"}}`)
+	defer func(f func(string, string, string, string) (*unstructured.Unstructured, error)) { getOwnerResource = f }(getOwnerResource)
+	getOwnerResource = func(apiVersion, kind, namespace, name string) (*unstructured.Unstructured, error) {
+		if kind != owner.GetKind() || namespace != owner.GetNamespace() || name != owner.GetName() {
+			return nil, errors.New("not found")
+		}
+		return owner.DeepCopy(), nil
+	}
+	allInScope := k8smanifest.ObjectReferenceList{{Kind: "ReplicaSet"}}
+	controller := "system:serviceaccount:kube-system:replicaset-controller"
+	controllers := []string{"system:serviceaccount:kube-system:*-controller"}
+
+	standalone := loadTestObject(t, `{"apiVersion": "v1", "kind": "Pod", "metadata": {"name": "sample-pod", "namespace": "sample-ns"}}`)
+	if isOwnedByController(standalone, allInScope, controller, controllers) {
+		t.Error("standalone pod should be verified")
+	}
+
+	owned := standalone.DeepCopy()
+	isController := true
+	owned.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "sample-rs", UID: "6b5a3a1e", Controller: &isController},
+	})
+	if !isOwnedByController(*owned, allInScope, controller, controllers) {
+		t.Error("pod owned by a replicaset should be skipped")
+	}
+
+	// a user who is not a controller copies the uid of the owner
+	if isOwnedByController(*owned, allInScope, "sample-user", controllers) {
+		t.Error("pod created by a user who is not a controller should be verified")
+	}
+
+	// no controllers are configured
+	if isOwnedByController(*owned, allInScope, controller, nil) {
+		t.Error("pod should be verified if no controllers are configured")
+	}
+
+	// the owner is out of scope
+	if isOwnedByController(*owned, k8smanifest.ObjectReferenceList{{Kind: "Pod"}}, controller, controllers) {
+		t.Error("pod owned by an out-of-scope replicaset should be verified")
+	}
+
+	// a fake ownerReference with a wrong uid
+	owned.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "sample-rs", UID: "00000000", Controller: &isController},
+	})
+	if isOwnedByController(*owned, allInScope, controller, controllers) {
+		t.Error("pod with an ownerReference of a wrong uid should be verified")
+	}
+
+	// a fake ownerReference to a missing owner
+	owned.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: "missing-rs", UID: "6b5a3a1e", Controller: &isController},
+	})
+	if isOwnedByController(*owned, allInScope, controller, controllers) {
+		t.Error("pod with an ownerReference to a missing owner should be verified")
+	}
+
+	// an owner which is not a controller
+	notController := false
+	owned.SetOwnerReferences([]metav1.OwnerReference{
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "sample-cm", UID: "7c6b4b2f", Controller: &notController},
+	})
+	if isOwnedByController(*owned, allInScope, controller, controllers) {
+		t.Error("pod without a controller owner should be verified")
+	}
+}
+
+func TestCheckUpdateMutationWithUpdateVerification(t *testing.T) {
+	oldObj := loadTestObject(t, testSSAConfigMap)
+	oldObj.SetResourceVersion("100")
+	oldBytes, _ := json.Marshal(oldObj.Object)
+	newUpdateRequest := func(newObj *unstructured.Unstructured) admission.Request {
+		newBytes, _ := json.Marshal(newObj.Object)
+		return admission.Request{
+			AdmissionRequest: admv1.AdmissionRequest{
+				Operation: admv1.Update,
+				OldObject: runtime.RawExtension{Raw: oldBytes},
+				Object:    runtime.RawExtension{Raw: newBytes},
+			},
+		}
+	}
+	paramObj := &k8smnfconfig.ParameterObject{}
+
+	// metadata-only change
+	metadataChanged := oldObj.DeepCopy()
+	metadataChanged.SetResourceVersion("101")
+	metadataChanged.SetGeneration(2)
+	// spec change
+	dataChanged := oldObj.DeepCopy()
+	_ = unstructured.SetNestedField(dataChanged.Object, "changed", "data", "key1")
+
+	for _, mode := range []string{"", k8smnfconfig.UpdateVerificationOnChange} {
+		rhconfig := &k8smnfconfig.RequestHandlerConfig{UpdateVerification: mode}
+		if r := checkUpdateMutation(newUpdateRequest(metadataChanged), *metadataChanged, paramObj, rhconfig); r == nil || !r.Allow {
+			t.Errorf("metadata-only update should be skipped in mode `%s`: %v", mode, r)
+		}
+		if r := checkUpdateMutation(newUpdateRequest(dataChanged), *dataChanged, paramObj, rhconfig); r != nil {
+			t.Errorf("update of the content should be verified in mode `%s`: %v", mode, r)
+		}
+	}
+
+	rhconfig := &k8smnfconfig.RequestHandlerConfig{UpdateVerification: k8smnfconfig.UpdateVerificationAlways}
+	if r := checkUpdateMutation(newUpdateRequest(metadataChanged), *metadataChanged, paramObj, rhconfig); r != nil {
+		t.Errorf("every update should be verified in mode `Always`: %v", r)
+	}
+}
+
+func TestGetManifestRefFromLabels(t *testing.T) {
+	refTemplate := `registry.example.com/{{ label "app.kubernetes.io/part-of" }}-manifests:latest`
+	obj := loadTestObject(t, testSSAConfigMap)
+	obj.SetLabels(map[string]string{"app.kubernetes.io/part-of": "sample-app"})
+
+	ref, err := getManifestRefFromLabels(obj, refTemplate, nil)
+	if err != nil || ref != "registry.example.com/sample-app-manifests:latest" {
+		t.Errorf("manifest ref should be rendered from the label: %s, %v", ref, err)
+	}
+
+	// other variables
+	ref, _ = getManifestRefFromLabels(obj, `registry.example.com/{{ .Namespace }}/{{ label "app.kubernetes.io/part-of" }}:latest`, nil)
+	if ref != "registry.example.com/sample-ns/sample-app:latest" {
+		t.Errorf("namespace should be rendered: %s", ref)
+	}
+
+	// label is not set
+	noLabel := loadTestObject(t, testSSAConfigMap)
+	if ref, err := getManifestRefFromLabels(noLabel, refTemplate, nil); ref != "" || err != nil {
+		t.Errorf("manifest ref should not be rendered without the label: %s, %v", ref, err)
+	}
+
+	// label is not under signature
+	ignoreFields := k8smanifest.ObjectFieldBindingList{
+		{Fields: []string{"metadata.labels.*"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "ConfigMap"}}},
+	}
+	if _, err := getManifestRefFromLabels(obj, refTemplate, ignoreFields); err == nil {
+		t.Error("label in ignore fields should not be used for the manifest ref")
+	}
+}
@@ -0,0 +1,43 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+)
+
+// getStaleSignatureMessage returns a deny message if the signature is older than maxAge, otherwise an empty string.
+// The signed time is the integration time of the transparency log entry. If it is not available,
+// the signature is denied unless the failure policy is "Ignore". The clock skew allowance extends maxAge.
+func getStaleSignatureMessage(signedTime *time.Time, maxAge, skew time.Duration, failurePolicy string, now time.Time) string {
+	if maxAge <= 0 {
+		return ""
+	}
+	if signedTime == nil {
+		if failurePolicy == k8smnfconfig.FailurePolicyIgnore {
+			return ""
+		}
+		return "Signature verification is required for this request, but the signed time is not found to check the signature age."
+	}
+	if age := now.Sub(*signedTime); age > maxAge+skew {
+		return fmt.Sprintf("Signature verification is required for this request, but the signature is too old; signed at %s (max age: %s)", signedTime.Format(time.RFC3339), maxAge)
+	}
+	return ""
+}
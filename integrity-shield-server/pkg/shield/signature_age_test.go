@@ -0,0 +1,67 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+	"time"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+)
+
+func TestGetStaleSignatureMessage(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	maxAge := 30 * 24 * time.Hour
+
+	fresh := now.Add(-24 * time.Hour)
+	if msg := getStaleSignatureMessage(&fresh, maxAge, 0, "", now); msg != "" {
+		t.Errorf("fresh signature should be allowed: %s", msg)
+	}
+	stale := now.Add(-60 * 24 * time.Hour)
+	if msg := getStaleSignatureMessage(&stale, maxAge, 0, "", now); msg == "" {
+		t.Error("stale signature should be denied")
+	}
+	if msg := getStaleSignatureMessage(&stale, 0, 0, "", now); msg != "" {
+		t.Errorf("signature age should not be checked without max age: %s", msg)
+	}
+
+	// signed time is not available
+	if msg := getStaleSignatureMessage(nil, maxAge, 0, "", now); msg == "" {
+		t.Error("signature without signed time should be denied by default")
+	}
+	if msg := getStaleSignatureMessage(nil, maxAge, 0, k8smnfconfig.FailurePolicyIgnore, now); msg != "" {
+		t.Errorf("signature without signed time should be allowed by the failure policy: %s", msg)
+	}
+}
+
+func TestGetStaleSignatureMessageWithClockSkew(t *testing.T) {
+	now := time.Date(2021, 8, 1, 0, 0, 0, 0, time.UTC)
+	maxAge := time.Hour
+	skew := 5 * time.Minute
+
+	borderline := now.Add(-maxAge - 2*time.Minute)
+	if msg := getStaleSignatureMessage(&borderline, maxAge, 0, "", now); msg == "" {
+		t.Error("signature just over max age should be denied without the allowance")
+	}
+	if msg := getStaleSignatureMessage(&borderline, maxAge, skew, "", now); msg != "" {
+		t.Errorf("signature just over max age should be allowed with the allowance: %s", msg)
+	}
+	stale := now.Add(-maxAge - 10*time.Minute)
+	if msg := getStaleSignatureMessage(&stale, maxAge, skew, "", now); msg == "" {
+		t.Error("signature over max age and the allowance should be denied")
+	}
+}
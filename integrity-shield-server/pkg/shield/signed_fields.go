@@ -0,0 +1,159 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	k8smnfutil "github.com/sigstore/k8s-manifest-sigstore/pkg/util"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// The comma-separated fields covered by the signature, e.g. `spec.replicas,spec.template.spec.containers.*.image`.
+// The annotation is a part of the signed manifest, so the set of covered fields cannot be changed without re-signing.
+// With this annotation, only the covered fields are compared with the signed manifest and the other fields can vary.
+const SignedFieldsAnnotationKey = "integrityshield.io/signedFields"
+
+// getSignedFields returns the signed fields in the annotation of the signed manifest.
+// The live value is used only if it is compared with the signed manifest, i.e. it is not in the diff;
+// otherwise the signed value is taken from the diff. It is an error if the annotation matches an ignore field,
+// e.g. `metadata.annotations.*` or a strip metadata key, because then the annotation could be added to any
+// signed manifest without re-signing.
+func getSignedFields(resource unstructured.Unstructured, diff *mapnode.DiffResult, ignoreFields k8smanifest.ObjectFieldBindingList) ([]string, error) {
+	annotationField := "metadata.annotations." + SignedFieldsAnnotationKey
+	val, found := resource.GetAnnotations()[SignedFieldsAnnotationKey]
+	if diff != nil {
+		for _, item := range diff.Items {
+			if signedVal, ok := getSignedAnnotationFromDiffItem(item.Key, item.Values["after"]); ok {
+				val, found = signedVal, signedVal != ""
+			}
+		}
+	}
+	if !found {
+		return nil, nil
+	}
+	_, fields := ignoreFields.Match(resource)
+	for _, field := range fields {
+		if k8smnfutil.MatchPattern(field, annotationField) {
+			return nil, errors.New(fmt.Sprintf("the annotation `%s` is not covered by the signature because it matches ignore field `%s`", SignedFieldsAnnotationKey, field))
+		}
+	}
+	signedFields := []string{}
+	for _, f := range strings.Split(val, ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			signedFields = append(signedFields, f)
+		}
+	}
+	return signedFields, nil
+}
+
+// getSignedAnnotationFromDiffItem returns the signed value of the signed fields annotation if the diff item is
+// the annotation or its parent, e.g. `metadata.annotations` when the signed manifest has no annotations.
+func getSignedAnnotationFromDiffItem(key string, after interface{}) (string, bool) {
+	var annotations interface{}
+	switch key {
+	case "metadata.annotations." + SignedFieldsAnnotationKey:
+		if after == nil {
+			return "", true
+		}
+		return fmt.Sprint(after), true
+	case "metadata.annotations":
+		annotations = after
+	case "metadata":
+		if metadata, ok := after.(map[string]interface{}); ok {
+			annotations = metadata["annotations"]
+		}
+	default:
+		return "", false
+	}
+	if m, ok := annotations.(map[string]interface{}); ok {
+		if val, found := m[SignedFieldsAnnotationKey]; found && val != nil {
+			return fmt.Sprint(val), true
+		}
+	}
+	return "", true
+}
+
+// getUncoveredFields returns the fields of the diff which are not covered by the signed fields.
+// No fields are returned if the annotation itself differs from the signed manifest.
+// The second return value is true only if none of the covered fields differ from the signed manifest.
+func getUncoveredFields(diff *mapnode.DiffResult, signedFields []string) ([]string, bool) {
+	if diff == nil || diff.Size() == 0 || len(signedFields) == 0 {
+		return nil, false
+	}
+	fields := []string{}
+	all := true
+	for _, item := range diff.Items {
+		if isCoveredField(item.Key, "metadata.annotations."+SignedFieldsAnnotationKey) {
+			return nil, false
+		}
+		if isCoveredBySignedFields(item.Key, signedFields) {
+			all = false
+			continue
+		}
+		fields = append(fields, item.Key)
+	}
+	return fields, all
+}
+
+// getTrustedMetadataFields returns the metadata fields which the skip and scope rules and the lookup of
+// the signed manifest rely on, such as the name, labels and the manifest ref annotation.
+// They are always treated as covered even if the signed fields do not list them.
+func getTrustedMetadataFields(manifestRefAnnotation string) []string {
+	fields := []string{
+		"metadata.name",
+		"metadata.namespace",
+		"metadata.generateName",
+		"metadata.labels",
+		"metadata.ownerReferences",
+	}
+	keys := append([]string{}, signatureAnnotationKeys...)
+	keys = append(keys, ManifestDigestAnnotationKey, ManifestDigestSignatureAnnotationKey)
+	if manifestRefAnnotation != "" {
+		keys = append(keys, manifestRefAnnotation)
+	}
+	for _, key := range keys {
+		fields = append(fields, "metadata.annotations."+key)
+	}
+	return fields
+}
+
+func isCoveredBySignedFields(key string, signedFields []string) bool {
+	for _, f := range signedFields {
+		if isCoveredField(key, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// isCoveredField returns true if the field of the diff overlaps with the signed field, i.e. the signed field
+// is the same, a parent, or a child of the field. Each part of the signed field can be a glob pattern like `*`.
+func isCoveredField(key, signedField string) bool {
+	keyParts := strings.Split(key, ".")
+	fieldParts := strings.Split(signedField, ".")
+	for i := 0; i < len(keyParts) && i < len(fieldParts); i++ {
+		if !k8smnfutil.MatchPattern(fieldParts[i], keyParts[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,78 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+func TestGetUncoveredFields(t *testing.T) {
+	signedStr := `{"kind": "Deployment", "metadata": {"name": "sample-app", "annotations": {"integrityshield.io/signedFields": "spec.replicas,spec.template.spec.containers.*.image"}},
+		"spec": {"replicas": 1, "template": {"metadata": {"labels": {"app": "sample-app"}}, "spec": {"containers": [{"name": "app", "image": "sample-app:v1"}]}}}}`
+	signed, _ := mapnode.NewFromBytes([]byte(signedStr))
+	obj := loadTestObject(t, signedStr)
+	signedFields, err := getSignedFields(obj, nil, nil)
+	if err != nil || len(signedFields) != 2 {
+		t.Errorf("signed fields should be loaded from the annotation: %v", signedFields)
+	}
+
+	uncovered, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app", "annotations": {"integrityshield.io/signedFields": "spec.replicas,spec.template.spec.containers.*.image"}},
+		"spec": {"replicas": 1, "template": {"metadata": {"labels": {"app": "sample-app", "team": "changed"}}, "spec": {"containers": [{"name": "app", "image": "sample-app:v1"}]}}}}`))
+	if _, ok := getUncoveredFields(uncovered.Diff(signed), signedFields); !ok {
+		t.Error("change in an uncovered field should pass")
+	}
+
+	covered, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app", "annotations": {"integrityshield.io/signedFields": "spec.replicas,spec.template.spec.containers.*.image"}},
+		"spec": {"replicas": 1, "template": {"metadata": {"labels": {"app": "sample-app"}}, "spec": {"containers": [{"name": "app", "image": "sample-app:v2"}]}}}}`))
+	if _, ok := getUncoveredFields(covered.Diff(signed), signedFields); ok {
+		t.Error("change in a covered field should be denied")
+	}
+
+	annotation, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app", "annotations": {"integrityshield.io/signedFields": "spec.replicas"}},
+		"spec": {"replicas": 1, "template": {"metadata": {"labels": {"app": "sample-app"}}, "spec": {"containers": [{"name": "app", "image": "sample-app:v1"}]}}}}`))
+	if _, ok := getUncoveredFields(annotation.Diff(signed), []string{"spec.replicas"}); ok {
+		t.Error("change in the signed fields annotation should be denied")
+	}
+}
+
+func TestGetSignedFieldsFromSignedManifest(t *testing.T) {
+	signed, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app"}, "spec": {"replicas": 1}}`))
+	// the annotation is added to a signed manifest which does not have it
+	liveStr := `{"kind": "Deployment", "metadata": {"name": "sample-app", "annotations": {"integrityshield.io/signedFields": "spec.replicas"}}, "spec": {"replicas": 1}}`
+	live, _ := mapnode.NewFromBytes([]byte(liveStr))
+	signedFields, err := getSignedFields(loadTestObject(t, liveStr), live.Diff(signed), nil)
+	if err != nil || len(signedFields) != 0 {
+		t.Errorf("signed fields should be taken from the signed manifest, not the live object: %v", signedFields)
+	}
+
+	// the annotation is not covered by the signature
+	obj := loadTestObject(t, liveStr)
+	ignoreFields := k8smanifest.ObjectFieldBindingList{
+		{Fields: []string{"metadata.annotations.*"}, Objects: k8smanifest.ObjectReferenceList{{Kind: "Deployment"}}},
+	}
+	if _, err := getSignedFields(obj, nil, ignoreFields); err == nil {
+		t.Error("signed fields annotation matching an ignore field should be rejected")
+	}
+	profile := k8smnfconfig.RequestFilterProfile{StripMetadataKeys: []string{"integrityshield.io/*"}}
+	if _, err := getSignedFields(obj, nil, profile.GetStripMetadataIgnoreFields()); err == nil {
+		t.Error("signed fields annotation matching a strip metadata key should be rejected")
+	}
+}
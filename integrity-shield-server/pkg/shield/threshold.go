@@ -0,0 +1,118 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	log "github.com/sirupsen/logrus"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// verifyResourceWithThreshold verifies a resource with each key in the option separately and
+// counts the distinct signers with a valid signature. The result is verified only if at least `required`
+// distinct signers have signed the manifest. A key path listed more than once is used only once.
+// A signer is identified by the signer name in the result, or by the key path if the name is empty,
+// as it is for key-based verification.
+func verifyResourceWithThreshold(resource unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption, required int, timeout time.Duration) (*k8smanifest.VerifyResourceResult, int, error) {
+	keyPaths := []string{}
+	for _, keyPath := range strings.Split(vo.KeyPath, ",") {
+		if keyPath != "" && !containsString(keyPaths, keyPath) {
+			keyPaths = append(keyPaths, keyPath)
+		}
+	}
+	if len(keyPaths) < required {
+		return nil, 0, errors.New(fmt.Sprintf("%d signatures are required, but only %d keys are configured", required, len(keyPaths)))
+	}
+
+	var lastResult *k8smanifest.VerifyResourceResult
+	keyErrs := &keyVerificationErrors{}
+	signers := []string{}
+	signerNames := []string{}
+	for _, keyPath := range keyPaths {
+		kvo := *vo
+		kvo.KeyPath = keyPath
+		result, err := verifyResourceWithTimeout(resource, &kvo, timeout)
+		if err != nil {
+			log.Warningf("failed to verify resource with key `%s`; %s", keyPath, err.Error())
+			keyErrs.add(keyPath, err)
+			continue
+		}
+		lastResult = result
+		if !result.Verified {
+			continue
+		}
+		signer := result.Signer
+		if signer == "" {
+			signer = fmt.Sprintf("key:%s", keyPath)
+		} else if !containsString(signerNames, result.Signer) {
+			signerNames = append(signerNames, result.Signer)
+		}
+		if !containsString(signers, signer) {
+			signers = append(signers, signer)
+		}
+	}
+	if lastResult == nil {
+		return nil, 0, keyErrs
+	}
+	result := *lastResult
+	result.Verified = len(signers) >= required
+	if len(signerNames) > 0 {
+		result.Signer = strings.Join(signerNames, ",")
+	}
+	return &result, len(signers), nil
+}
+
+// keyVerificationErrors is the errors of the keys which failed to verify a resource.
+// Its cause is the error of the first key, so that e.g. a timeout is still detected.
+type keyVerificationErrors struct {
+	keyPaths []string
+	errs     []error
+}
+
+func (e *keyVerificationErrors) add(keyPath string, err error) {
+	e.keyPaths = append(e.keyPaths, keyPath)
+	e.errs = append(e.errs, err)
+}
+
+func (e *keyVerificationErrors) Error() string {
+	msgs := []string{}
+	for i, err := range e.errs {
+		msgs = append(msgs, fmt.Sprintf("key `%s`: %s", e.keyPaths[i], err.Error()))
+	}
+	return fmt.Sprintf("failed to verify resource with %d keys; %s", len(e.errs), strings.Join(msgs, "; "))
+}
+
+func (e *keyVerificationErrors) Cause() error {
+	if len(e.errs) == 0 {
+		return nil
+	}
+	return errors.Cause(e.errs[0])
+}
+
+func containsString(l []string, s string) bool {
+	for _, v := range l {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestVerifyResourceWithThreshold(t *testing.T) {
+	orgFunc := verifyResourceFunc
+	defer func() { verifyResourceFunc = orgFunc }()
+
+	validKeys := map[string]bool{}
+	verifyResourceFunc = func(obj unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption) (*k8smanifest.VerifyResourceResult, error) {
+		if validKeys[vo.KeyPath] {
+			return &k8smanifest.VerifyResourceResult{InScope: true, Verified: true, Signer: vo.KeyPath}, nil
+		}
+		return &k8smanifest.VerifyResourceResult{InScope: true, Verified: false}, nil
+	}
+
+	testcases := []struct {
+		name      string
+		validKeys []string
+		verified  bool
+		count     int
+	}{
+		{name: "below threshold", validKeys: []string{"key-a"}, verified: false, count: 1},
+		{name: "exactly at threshold", validKeys: []string{"key-a", "key-b"}, verified: true, count: 2},
+		{name: "above threshold", validKeys: []string{"key-a", "key-b", "key-c"}, verified: true, count: 3},
+	}
+	for _, tc := range testcases {
+		validKeys = map[string]bool{}
+		for _, k := range tc.validKeys {
+			validKeys[k] = true
+		}
+		vo := &k8smanifest.VerifyResourceOption{}
+		vo.KeyPath = "key-a,key-b,key-c"
+		result, count, err := verifyResourceWithThreshold(unstructured.Unstructured{}, vo, 2, 0)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tc.name, err.Error())
+			continue
+		}
+		if result.Verified != tc.verified {
+			t.Errorf("%s: expected verified %v, but got %v", tc.name, tc.verified, result.Verified)
+		}
+		if count != tc.count {
+			t.Errorf("%s: expected %d valid signatures, but got %d", tc.name, tc.count, count)
+		}
+	}
+}
+
+func TestVerifyResourceWithThresholdWithoutSignerNames(t *testing.T) {
+	orgFunc := verifyResourceFunc
+	defer func() { verifyResourceFunc = orgFunc }()
+
+	// key-based verification does not return the signer name
+	verifyResourceFunc = func(obj unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption) (*k8smanifest.VerifyResourceResult, error) {
+		switch vo.KeyPath {
+		case "key-a", "key-b":
+			return &k8smanifest.VerifyResourceResult{InScope: true, Verified: true}, nil
+		}
+		return &k8smanifest.VerifyResourceResult{InScope: true, Verified: false}, nil
+	}
+
+	vo := &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = "key-a,key-b,key-c"
+	result, count, err := verifyResourceWithThreshold(unstructured.Unstructured{}, vo, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if !result.Verified || count != 2 {
+		t.Errorf("signatures of two keys should be counted as two signers, but got verified %v with %d signatures", result.Verified, count)
+	}
+
+	// the same key listed twice is still counted once
+	vo.KeyPath = "key-a,key-a,key-c"
+	result, count, err = verifyResourceWithThreshold(unstructured.Unstructured{}, vo, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if result.Verified || count != 1 {
+		t.Errorf("a duplicated key should be counted once, but got verified %v with %d signatures", result.Verified, count)
+	}
+}
+
+func TestVerifyResourceWithThresholdCountsDistinctKeys(t *testing.T) {
+	orgFunc := verifyResourceFunc
+	defer func() { verifyResourceFunc = orgFunc }()
+
+	verifyResourceFunc = func(obj unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption) (*k8smanifest.VerifyResourceResult, error) {
+		switch vo.KeyPath {
+		case "key-a", "key-a-copy":
+			return &k8smanifest.VerifyResourceResult{InScope: true, Verified: true, Signer: "signer-a"}, nil
+		}
+		return nil, errors.New("no signature found")
+	}
+
+	// a key listed twice, e.g. in keyConfigs and keyPathList
+	vo := &k8smanifest.VerifyResourceOption{}
+	vo.KeyPath = "key-a,key-b,key-a"
+	result, count, err := verifyResourceWithThreshold(unstructured.Unstructured{}, vo, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if result.Verified || count != 1 {
+		t.Errorf("a duplicated key should be counted once, but got verified %v with %d signatures", result.Verified, count)
+	}
+
+	// two key files of the same signer
+	vo.KeyPath = "key-a,key-a-copy"
+	result, count, err = verifyResourceWithThreshold(unstructured.Unstructured{}, vo, 2, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if result.Verified || count != 1 {
+		t.Errorf("keys of the same signer should be counted once, but got verified %v with %d signatures", result.Verified, count)
+	}
+
+	// errors of each key are reported
+	vo.KeyPath = "key-b,key-c"
+	_, _, err = verifyResourceWithThreshold(unstructured.Unstructured{}, vo, 2, 0)
+	if err == nil {
+		t.Fatal("an error is expected if no key can verify the resource")
+	}
+	for _, key := range []string{"key-b", "key-c"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error of key `%s` should be reported: %s", key, err.Error())
+		}
+	}
+}
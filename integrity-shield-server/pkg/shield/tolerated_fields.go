@@ -0,0 +1,71 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// getToleratedFields returns the fields of the diff which are tolerated by any of the rules; resolved placeholders,
+// fields not covered by the signed fields, reordered unordered lists and values equal in the canonical form.
+// The second return value is true only if all differences are tolerated, so that the resource is verified
+// again only once ignoring the fields.
+func getToleratedFields(resource unstructured.Unstructured, diff *mapnode.DiffResult, ignoreFields k8smanifest.ObjectFieldBindingList, rhconfig *k8smnfconfig.RequestHandlerConfig) ([]string, bool, error) {
+	if diff == nil || diff.Size() == 0 {
+		return nil, false, nil
+	}
+	tolerated := map[string]bool{}
+	add := func(fields []string, _ bool) {
+		for _, f := range fields {
+			tolerated[f] = true
+		}
+	}
+	add(getResolvedPlaceholderFields(diff, rhconfig.Placeholders))
+	signedFields, err := getSignedFields(resource, diff, ignoreFields)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(signedFields) > 0 {
+		signedFields = append(signedFields, getTrustedMetadataFields(rhconfig.ManifestRefAnnotation)...)
+	}
+	add(getUncoveredFields(diff, signedFields))
+	add(getReorderedListFields(resource, diff, rhconfig.UnorderedLists))
+	if !rhconfig.DisableCanonicalization {
+		add(getCanonicallyEqualFields(diff))
+	}
+	fields := []string{}
+	for _, item := range diff.Items {
+		if !tolerated[item.Key] {
+			return nil, false, nil
+		}
+		fields = append(fields, item.Key)
+	}
+	return fields, true, nil
+}
+
+// newToleratedIgnoreField returns an ignore field binding of the tolerated fields of the resource.
+func newToleratedIgnoreField(resource unstructured.Unstructured, fields []string) k8smanifest.ObjectFieldBinding {
+	return k8smanifest.ObjectFieldBinding{
+		Fields: fields,
+		Objects: k8smanifest.ObjectReferenceList{
+			{Kind: resource.GetKind(), Name: resource.GetName()},
+		},
+	}
+}
@@ -0,0 +1,90 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"strings"
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestGetToleratedFields(t *testing.T) {
+	rhconfig := &k8smnfconfig.RequestHandlerConfig{
+		Placeholders: []k8smnfconfig.PlaceholderPattern{
+			{Placeholder: "${CONFIG_HASH}", Pattern: "[a-f0-9]{8}"},
+		},
+	}
+	signed, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app", "annotations": {"hash": "${CONFIG_HASH}"}}, "spec": {"replicas": 1.0, "paused": "false"}}`))
+	diffOf := func(liveStr string) (*mapnode.DiffResult, []byte) {
+		live, _ := mapnode.NewFromBytes([]byte(liveStr))
+		return live.Diff(signed), []byte(liveStr)
+	}
+
+	// a resolved placeholder and a value in another representation are tolerated together
+	diff, liveBytes := diffOf(`{"kind": "Deployment", "metadata": {"name": "sample-app", "annotations": {"hash": "1a2b3c4d"}}, "spec": {"replicas": 1.0, "paused": false}}`)
+	resource := loadTestObject(t, string(liveBytes))
+	fields, ok, err := getToleratedFields(resource, diff, nil, rhconfig)
+	if err != nil || !ok || len(fields) != 2 {
+		t.Errorf("differences tolerated by different rules should be tolerated at once; fields: %v, err: %v", fields, err)
+	}
+
+	// canonicalization is disabled
+	rhconfig.DisableCanonicalization = true
+	if _, ok, _ := getToleratedFields(resource, diff, nil, rhconfig); ok {
+		t.Error("value in another representation should not be tolerated if canonicalization is disabled")
+	}
+	rhconfig.DisableCanonicalization = false
+
+	// a difference which is not tolerated by any rule
+	diff, liveBytes = diffOf(`{"kind": "Deployment", "metadata": {"name": "sample-app", "annotations": {"hash": "1a2b3c4d"}}, "spec": {"replicas": 2.0, "paused": false}}`)
+	resource = loadTestObject(t, string(liveBytes))
+	if fields, ok, _ := getToleratedFields(resource, diff, nil, rhconfig); ok {
+		t.Errorf("changed value should not be tolerated; fields: %v", fields)
+	}
+}
+
+func TestGetToleratedFieldsKeepsTrustedMetadata(t *testing.T) {
+	rhconfig := &k8smnfconfig.RequestHandlerConfig{ManifestRefAnnotation: "example.com/manifestRef"}
+	signedStr := `{"kind": "Deployment", "metadata": {"name": "sample-app", "labels": {"app": "sample-app"},
+		"annotations": {"integrityshield.io/signedFields": "spec.replicas", "example.com/manifestRef": "registry.example.com/sample-app:v1"}}, "spec": {"replicas": 1, "paused": false}}`
+	signed, _ := mapnode.NewFromBytes([]byte(signedStr))
+	diffOf := func(liveStr string) (*mapnode.DiffResult, unstructured.Unstructured) {
+		live, _ := mapnode.NewFromBytes([]byte(liveStr))
+		return live.Diff(signed), loadTestObject(t, liveStr)
+	}
+
+	// a field which is not listed in the signed fields
+	diff, resource := diffOf(strings.Replace(signedStr, `"paused": false`, `"paused": true`, 1))
+	if _, ok, err := getToleratedFields(resource, diff, nil, rhconfig); err != nil || !ok {
+		t.Errorf("change in a field not covered by the signed fields should be tolerated; err: %v", err)
+	}
+
+	changes := map[string][2]string{
+		"manifest ref annotation": {"registry.example.com/sample-app:v1", "registry.example.com/other-app:v1"},
+		"label":                   {`"app": "sample-app"`, `"app": "other-app"`},
+		"name":                    {`"name": "sample-app"`, `"name": "other-app"`},
+	}
+	for name, change := range changes {
+		diff, resource := diffOf(strings.Replace(signedStr, change[0], change[1], 1))
+		if _, ok, _ := getToleratedFields(resource, diff, nil, rhconfig); ok {
+			t.Errorf("change in the %s should be denied even if the signed fields do not list it", name)
+		}
+	}
+}
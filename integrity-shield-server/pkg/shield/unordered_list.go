@@ -0,0 +1,204 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	k8smnfutil "github.com/sigstore/k8s-manifest-sigstore/pkg/util"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// getReorderedListFields returns the fields of the diff which are in the configured unordered lists.
+// The signed lists are reconstructed from the diff, and they are compared with the lists of the resource
+// by the key field of the elements, regardless of the order.
+// The second return value is true only if all differences are in such lists which have the same elements.
+func getReorderedListFields(resource unstructured.Unstructured, diff *mapnode.DiffResult, lists []k8smnfconfig.UnorderedList) ([]string, bool) {
+	if diff == nil || diff.Size() == 0 || len(lists) == 0 {
+		return nil, false
+	}
+	// signed lists and the fields of the diff by the path of the list in the resource
+	signedLists := map[string][]interface{}{}
+	listKeys := map[string]string{}
+	listFields := map[string][]string{}
+	// lists which cannot be reconstructed from the diff
+	invalidLists := map[string]bool{}
+	listPaths := []string{}
+	all := true
+	for _, item := range diff.Items {
+		listPath, index, rest, key, ok := matchUnorderedList(item.Key, lists)
+		if !ok {
+			all = false
+			continue
+		}
+		if _, found := listKeys[listPath]; !found {
+			listKeys[listPath] = key
+			listPaths = append(listPaths, listPath)
+		}
+		listFields[listPath] = append(listFields[listPath], item.Key)
+		after, afterFound := item.Values["after"]
+		if _, beforeFound := item.Values["before"]; !beforeFound || !afterFound {
+			invalidLists[listPath] = true
+			continue
+		}
+		signed, found := signedLists[listPath]
+		if !found {
+			live, ok := getNestedValue(resource.Object, strings.Split(listPath, ".")).([]interface{})
+			if !ok {
+				invalidLists[listPath] = true
+				continue
+			}
+			signed = runtime.DeepCopyJSONValue(live).([]interface{})
+			signedLists[listPath] = signed
+		}
+		if !setNestedValue(signed, append([]string{index}, rest...), after) {
+			invalidLists[listPath] = true
+		}
+	}
+	fields := []string{}
+	for _, listPath := range listPaths {
+		if invalidLists[listPath] {
+			all = false
+			continue
+		}
+		live := getNestedValue(resource.Object, strings.Split(listPath, ".")).([]interface{})
+		if !isSameUnorderedList(live, signedLists[listPath], listKeys[listPath]) {
+			all = false
+			continue
+		}
+		fields = append(fields, listFields[listPath]...)
+	}
+	return fields, all
+}
+
+// matchUnorderedList returns the path of the list instance, the element index and the remaining parts of the field.
+func matchUnorderedList(field string, lists []k8smnfconfig.UnorderedList) (string, string, []string, string, bool) {
+	fieldParts := strings.Split(field, ".")
+	for _, l := range lists {
+		pathParts := strings.Split(l.Path, ".")
+		if len(fieldParts) <= len(pathParts) {
+			continue
+		}
+		matched := true
+		for i, p := range pathParts {
+			if !k8smnfutil.MatchPattern(p, fieldParts[i]) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+		if _, err := strconv.Atoi(fieldParts[len(pathParts)]); err != nil {
+			continue
+		}
+		return strings.Join(fieldParts[:len(pathParts)], "."), fieldParts[len(pathParts)], fieldParts[len(pathParts)+1:], l.Key, true
+	}
+	return "", "", nil, "", false
+}
+
+// isSameUnorderedList returns true if both lists have exactly the same elements for each value of the key field.
+// Only the Go types of the values are unified through JSON, e.g. int64 and float64 for the same number;
+// the values themselves are never normalized, so "1.10" and "1.1" are different.
+func isSameUnorderedList(live, signed []interface{}, key string) bool {
+	if len(live) != len(signed) {
+		return false
+	}
+	live, liveOk := toJSONValue(live).([]interface{})
+	signed, signedOk := toJSONValue(signed).([]interface{})
+	if !liveOk || !signedOk {
+		return false
+	}
+	liveElements := map[string]interface{}{}
+	for _, e := range live {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		k := fmt.Sprint(m[key])
+		if _, found := liveElements[k]; found {
+			return false
+		}
+		liveElements[k] = m
+	}
+	for _, e := range signed {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		liveElement, found := liveElements[fmt.Sprint(m[key])]
+		if !found || !reflect.DeepEqual(liveElement, m) {
+			return false
+		}
+	}
+	return true
+}
+
+func toJSONValue(v interface{}) interface{} {
+	vBytes, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	var jsonValue interface{}
+	if err := json.Unmarshal(vBytes, &jsonValue); err != nil {
+		return nil
+	}
+	return jsonValue
+}
+
+func getNestedValue(obj interface{}, parts []string) interface{} {
+	for _, p := range parts {
+		switch val := obj.(type) {
+		case map[string]interface{}:
+			obj = val[p]
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i < 0 || i >= len(val) {
+				return nil
+			}
+			obj = val[i]
+		default:
+			return nil
+		}
+	}
+	return obj
+}
+
+func setNestedValue(obj interface{}, parts []string, value interface{}) bool {
+	parent := getNestedValue(obj, parts[:len(parts)-1])
+	last := parts[len(parts)-1]
+	switch val := parent.(type) {
+	case map[string]interface{}:
+		val[last] = value
+		return true
+	case []interface{}:
+		i, err := strconv.Atoi(last)
+		if err != nil || i < 0 || i >= len(val) {
+			return false
+		}
+		val[i] = value
+		return true
+	}
+	return false
+}
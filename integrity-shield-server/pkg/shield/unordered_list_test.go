@@ -0,0 +1,60 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+func TestGetReorderedListFields(t *testing.T) {
+	lists := []k8smnfconfig.UnorderedList{{Path: "spec.template.spec.containers.*.env", Key: "name"}}
+	signed, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app"},
+		"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "A", "value": "1"}, {"name": "B", "value": "2"}]}]}}}}`))
+
+	reorderedStr := `{"kind": "Deployment", "metadata": {"name": "sample-app"},
+		"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "B", "value": "2"}, {"name": "A", "value": "1"}]}]}}}}`
+	reordered, _ := mapnode.NewFromBytes([]byte(reorderedStr))
+	if _, ok := getReorderedListFields(loadTestObject(t, reorderedStr), reordered.Diff(signed), lists); !ok {
+		t.Error("reordered env vars should match the signed manifest")
+	}
+
+	// order-sensitive for unlisted paths
+	if _, ok := getReorderedListFields(loadTestObject(t, reorderedStr), reordered.Diff(signed), nil); ok {
+		t.Error("reordered lists should not match without the config")
+	}
+
+	// values are compared exactly, not as numbers
+	versionSignedStr := `{"kind": "Deployment", "metadata": {"name": "sample-app"},
+		"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "A", "value": "1.10"}, {"name": "B", "value": "2"}]}]}}}}`
+	versionSigned, _ := mapnode.NewFromBytes([]byte(versionSignedStr))
+	versionStr := `{"kind": "Deployment", "metadata": {"name": "sample-app"},
+		"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "B", "value": "2"}, {"name": "A", "value": "1.1"}]}]}}}}`
+	version, _ := mapnode.NewFromBytes([]byte(versionStr))
+	if _, ok := getReorderedListFields(loadTestObject(t, versionStr), version.Diff(versionSigned), lists); ok {
+		t.Error("reordered env vars with a numerically-equal but different value should not match")
+	}
+
+	changedStr := `{"kind": "Deployment", "metadata": {"name": "sample-app"},
+		"spec": {"template": {"spec": {"containers": [{"name": "app", "env": [{"name": "B", "value": "3"}, {"name": "A", "value": "1"}]}]}}}}`
+	changed, _ := mapnode.NewFromBytes([]byte(changedStr))
+	if _, ok := getReorderedListFields(loadTestObject(t, changedStr), changed.Diff(signed), lists); ok {
+		t.Error("reordered env vars with a changed value should not match")
+	}
+}
@@ -0,0 +1,70 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+)
+
+// VerificationDetail breaks down a verification result, so that each outcome can be checked separately.
+type VerificationDetail struct {
+	Signature  DimensionResult `json:"signature"`
+	Content    DimensionResult `json:"content"`
+	Provenance DimensionResult `json:"provenance"`
+}
+
+type DimensionResult struct {
+	Checked bool   `json:"checked"`
+	Valid   bool   `json:"valid"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// newVerificationDetail sets each dimension from the result of VerifyResource.
+func newVerificationDetail(result *k8smanifest.VerifyResourceResult, verifyErr error) *VerificationDetail {
+	detail := &VerificationDetail{
+		Provenance: DimensionResult{
+			Checked: false,
+			Reason:  "provenance is not verified by integrity shield",
+		},
+	}
+	if verifyErr != nil || result == nil {
+		reason := "no result from VerifyResource"
+		if verifyErr != nil {
+			reason = verifyErr.Error()
+		}
+		detail.Signature = DimensionResult{Checked: true, Valid: false, Reason: reason}
+		detail.Content = DimensionResult{Checked: false, Reason: "signature is not verified"}
+		return detail
+	}
+
+	if result.Signer != "" {
+		detail.Signature = DimensionResult{Checked: true, Valid: true, Reason: fmt.Sprintf("signed by %s", result.Signer)}
+	} else {
+		detail.Signature = DimensionResult{Checked: true, Valid: false, Reason: "no valid signature is found"}
+	}
+
+	if result.Diff != nil && result.Diff.Size() > 0 {
+		detail.Content = DimensionResult{Checked: true, Valid: false, Reason: fmt.Sprintf("diff found: %s", result.Diff.String())}
+	} else if result.Signer != "" {
+		detail.Content = DimensionResult{Checked: true, Valid: true}
+	} else {
+		detail.Content = DimensionResult{Checked: false, Reason: "no signed manifest to compare"}
+	}
+	return detail
+}
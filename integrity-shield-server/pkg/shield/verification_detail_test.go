@@ -0,0 +1,56 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+func TestNewVerificationDetail(t *testing.T) {
+	// signed and matched
+	detail := newVerificationDetail(&k8smanifest.VerifyResourceResult{InScope: true, Verified: true, Signer: "signer@signer.com"}, nil)
+	if !detail.Signature.Valid || !detail.Content.Valid || detail.Provenance.Checked {
+		t.Errorf("unexpected detail for a verified resource: %v", detail)
+	}
+
+	// signed but changed
+	signed, _ := mapnode.NewFromBytes([]byte(`{"data":{"key1":"val1"}}`))
+	changed, _ := mapnode.NewFromBytes([]byte(`{"data":{"key1":"val2"}}`))
+	detail = newVerificationDetail(&k8smanifest.VerifyResourceResult{InScope: true, Signer: "signer@signer.com", Diff: signed.Diff(changed)}, nil)
+	if !detail.Signature.Valid {
+		t.Errorf("signature should be valid: %v", detail.Signature)
+	}
+	if !detail.Content.Checked || detail.Content.Valid {
+		t.Errorf("content should not match: %v", detail.Content)
+	}
+
+	// no signature
+	detail = newVerificationDetail(&k8smanifest.VerifyResourceResult{InScope: true}, nil)
+	if detail.Signature.Valid || detail.Content.Checked {
+		t.Errorf("unexpected detail for an unsigned resource: %v", detail)
+	}
+
+	// error
+	detail = newVerificationDetail(nil, errors.New("failed to get signature"))
+	if detail.Signature.Valid || detail.Signature.Reason != "failed to get signature" {
+		t.Errorf("unexpected detail for an error: %v", detail.Signature)
+	}
+}
@@ -0,0 +1,126 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// maxTimedOutVerifications is the number of timed-out VerifyResource calls which can be still running.
+// VerifyResource cannot be cancelled, so new calls fail fast while this many calls are left behind.
+const maxTimedOutVerifications = 16
+
+// verifyResourceFunc can be replaced in tests
+var verifyResourceFunc = k8smanifest.VerifyResource
+
+// the number of timed-out calls which are still running
+var timedOutVerifications = 0
+var timedOutVerificationsMutex sync.Mutex
+
+var timedOutVerificationsGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+	Name: "integrityshield_verify_timed_out_calls",
+	Help: "The number of timed-out VerifyResource calls which are still running.",
+})
+
+func init() {
+	prometheus.MustRegister(timedOutVerificationsGauge)
+}
+
+// verificationTimeoutError is returned when VerifyResource does not finish within the timeout,
+// or when too many timed-out calls are still running.
+type verificationTimeoutError struct {
+	message string
+}
+
+func (e *verificationTimeoutError) Error() string {
+	return e.message
+}
+
+func isVerificationTimeout(err error) bool {
+	_, ok := errors.Cause(err).(*verificationTimeoutError)
+	return ok
+}
+
+type verifyResourceResponse struct {
+	result *k8smanifest.VerifyResourceResult
+	err    error
+}
+
+// verifyResourceWithTimeout calls VerifyResource and gives up after the timeout so that
+// a slow image pull or signature verification does not consume the whole webhook deadline.
+// No timeout is applied if timeout is 0.
+func verifyResourceWithTimeout(resource unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption, timeout time.Duration) (*k8smanifest.VerifyResourceResult, error) {
+	if !verifyBreaker.allow() {
+		return nil, errVerificationCircuitOpen
+	}
+	result, err := callVerifyResourceWithTimeout(resource, vo, timeout)
+	verifyBreaker.record(isBackendFailure(err))
+	return result, err
+}
+
+func callVerifyResourceWithTimeout(resource unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption, timeout time.Duration) (*k8smanifest.VerifyResourceResult, error) {
+	if timeout == 0 {
+		return verifyResourceFunc(resource, vo)
+	}
+	timedOutVerificationsMutex.Lock()
+	running := timedOutVerifications
+	timedOutVerificationsMutex.Unlock()
+	if running >= maxTimedOutVerifications {
+		return nil, &verificationTimeoutError{message: fmt.Sprintf("verification is skipped because %d timed-out verifications are still running", running)}
+	}
+	// buffered so that the goroutine can exit even after the timeout
+	ch := make(chan verifyResourceResponse, 1)
+	// finished and timedOut are guarded by timedOutVerificationsMutex
+	finished := false
+	timedOut := false
+	verify := verifyResourceFunc
+	go func() {
+		result, err := verify(resource, vo)
+		ch <- verifyResourceResponse{result: result, err: err}
+		timedOutVerificationsMutex.Lock()
+		defer timedOutVerificationsMutex.Unlock()
+		finished = true
+		if timedOut {
+			setTimedOutVerifications(timedOutVerifications - 1)
+		}
+	}()
+	select {
+	case res := <-ch:
+		return res.result, res.err
+	case <-time.After(timeout):
+		timedOutVerificationsMutex.Lock()
+		defer timedOutVerificationsMutex.Unlock()
+		if !finished {
+			timedOut = true
+			setTimedOutVerifications(timedOutVerifications + 1)
+		}
+		return nil, &verificationTimeoutError{message: fmt.Sprintf("verification timed out; VerifyResource did not finish within %s", timeout.String())}
+	}
+}
+
+// setTimedOutVerifications must be called with timedOutVerificationsMutex held.
+func setTimedOutVerifications(n int) {
+	timedOutVerifications = n
+	timedOutVerificationsGauge.Set(float64(n))
+}
@@ -0,0 +1,113 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestVerifyResourceWithTimeout(t *testing.T) {
+	orgFunc := verifyResourceFunc
+	defer func() { verifyResourceFunc = orgFunc }()
+
+	// slow verification
+	verifyResourceFunc = func(obj unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption) (*k8smanifest.VerifyResourceResult, error) {
+		time.Sleep(500 * time.Millisecond)
+		return &k8smanifest.VerifyResourceResult{Verified: true}, nil
+	}
+	_, err := verifyResourceWithTimeout(unstructured.Unstructured{}, &k8smanifest.VerifyResourceOption{}, 50*time.Millisecond)
+	if err == nil || !strings.Contains(err.Error(), "timed out") || !isVerificationTimeout(err) {
+		t.Errorf("slow verification should time out; err: %v", err)
+	}
+
+	// fast verification
+	verifyResourceFunc = func(obj unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption) (*k8smanifest.VerifyResourceResult, error) {
+		return &k8smanifest.VerifyResourceResult{Verified: true}, nil
+	}
+	result, err := verifyResourceWithTimeout(unstructured.Unstructured{}, &k8smanifest.VerifyResourceOption{}, 1*time.Second)
+	if err != nil {
+		t.Errorf("fast verification should not time out; err: %s", err.Error())
+		return
+	}
+	if !result.Verified {
+		t.Error("result of fast verification should be returned")
+	}
+}
+
+func TestTimedOutVerificationsAreBounded(t *testing.T) {
+	orgFunc := verifyResourceFunc
+	defer func() { verifyResourceFunc = orgFunc }()
+	// calls left behind by other tests
+	waitUntil(t, func() bool { return getTimedOutVerifications() == 0 })
+
+	release := make(chan struct{})
+	calls := 0
+	var callsMutex sync.Mutex
+	getCalls := func() int {
+		callsMutex.Lock()
+		defer callsMutex.Unlock()
+		return calls
+	}
+	verifyResourceFunc = func(obj unstructured.Unstructured, vo *k8smanifest.VerifyResourceOption) (*k8smanifest.VerifyResourceResult, error) {
+		callsMutex.Lock()
+		calls++
+		callsMutex.Unlock()
+		<-release
+		return &k8smanifest.VerifyResourceResult{Verified: true}, nil
+	}
+	for i := 0; i < maxTimedOutVerifications; i++ {
+		if _, err := callVerifyResourceWithTimeout(unstructured.Unstructured{}, &k8smanifest.VerifyResourceOption{}, time.Millisecond); !isVerificationTimeout(err) {
+			t.Errorf("blocked verification should time out; err: %v", err)
+		}
+	}
+	waitUntil(t, func() bool { return getCalls() == maxTimedOutVerifications })
+
+	// no more goroutines are started
+	if _, err := callVerifyResourceWithTimeout(unstructured.Unstructured{}, &k8smanifest.VerifyResourceOption{}, time.Second); !isVerificationTimeout(err) {
+		t.Errorf("verification should fail fast while too many timed-out calls are running; err: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if calls := getCalls(); calls != maxTimedOutVerifications {
+		t.Errorf("timed-out calls should be bounded: %d", calls)
+	}
+
+	// the slots are released when the calls return
+	close(release)
+	waitUntil(t, func() bool { return getTimedOutVerifications() == 0 })
+}
+
+func getTimedOutVerifications() int {
+	timedOutVerificationsMutex.Lock()
+	defer timedOutVerificationsMutex.Unlock()
+	return timedOutVerifications
+}
+
+func waitUntil(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in time")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
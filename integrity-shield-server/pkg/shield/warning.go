@@ -0,0 +1,85 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"fmt"
+	"strings"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// paths to pod spec in workload resources
+var podSpecPaths = [][]string{
+	{"spec"},
+	{"spec", "template", "spec"},
+	{"spec", "jobTemplate", "spec", "template", "spec"},
+}
+
+var containerFieldNames = []string{"initContainers", "containers", "ephemeralContainers"}
+
+// getSoftPolicyWarnings returns the warnings for the soft policies enabled in the config.
+func getSoftPolicyWarnings(resource unstructured.Unstructured, config k8smnfconfig.WarningConfig) []string {
+	// image policies are skipped for resources without pod spec, e.g. ConfigMap and Service
+	if !hasContainerImages(resource) {
+		return nil
+	}
+	warnings := []string{}
+	if config.ImageNotPinnedByDigest {
+		for _, image := range getContainerImages(resource) {
+			if !strings.Contains(image, "@sha256:") {
+				warnings = append(warnings, fmt.Sprintf("image not pinned by digest: %s", image))
+			}
+		}
+	}
+	if len(warnings) == 0 {
+		return nil
+	}
+	return warnings
+}
+
+// hasContainerImages returns true if a resource has a pod spec with container images.
+func hasContainerImages(resource unstructured.Unstructured) bool {
+	return len(getContainerImages(resource)) > 0
+}
+
+// getContainerImages returns all container images in the pod spec of a resource.
+func getContainerImages(resource unstructured.Unstructured) []string {
+	images := []string{}
+	for _, specPath := range podSpecPaths {
+		for _, fieldName := range containerFieldNames {
+			fields := append(append([]string{}, specPath...), fieldName)
+			containers, found, err := unstructured.NestedSlice(resource.Object, fields...)
+			if err != nil || !found {
+				continue
+			}
+			for _, c := range containers {
+				container, ok := c.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				image, ok := container["image"].(string)
+				if !ok || image == "" {
+					continue
+				}
+				images = append(images, image)
+			}
+		}
+	}
+	return images
+}
@@ -0,0 +1,75 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package shield
+
+import (
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+)
+
+const testDeployment = `{
+	"apiVersion": "apps/v1",
+	"kind": "Deployment",
+	"metadata": {
+		"name": "sample-app",
+		"namespace": "sample-ns"
+	},
+	"spec": {
+		"replicas": 1,
+		"template": {
+			"spec": {
+				"initContainers": [
+					{"name": "init", "image": "busybox@sha256:b5cfd4befc119a590ca1a81d6bb0fa1fb19f1fbebd0397f25fae164abe1e8a6a"}
+				],
+				"containers": [
+					{"name": "app", "image": "nginx:1.21"}
+				]
+			}
+		}
+	}
+}`
+
+func TestGetSoftPolicyWarnings(t *testing.T) {
+	obj := loadTestObject(t, testDeployment)
+
+	images := getContainerImages(obj)
+	if len(images) != 2 {
+		t.Errorf("unexpected images: %v", images)
+	}
+
+	warnings := getSoftPolicyWarnings(obj, k8smnfconfig.WarningConfig{ImageNotPinnedByDigest: true})
+	if len(warnings) != 1 || warnings[0] != "image not pinned by digest: nginx:1.21" {
+		t.Errorf("unexpected warnings: %v", warnings)
+	}
+
+	warnings = getSoftPolicyWarnings(obj, k8smnfconfig.WarningConfig{})
+	if len(warnings) != 0 {
+		t.Errorf("no warnings are expected if soft policies are disabled: %v", warnings)
+	}
+}
+
+func TestGetSoftPolicyWarningsWithoutImages(t *testing.T) {
+	obj := loadTestObject(t, testSSAConfigMap)
+	if hasContainerImages(obj) {
+		t.Errorf("ConfigMap should not have container images")
+	}
+	warnings := getSoftPolicyWarnings(obj, k8smnfconfig.WarningConfig{ImageNotPinnedByDigest: true})
+	if warnings != nil {
+		t.Errorf("image policies should be skipped for ConfigMap: %v", warnings)
+	}
+}
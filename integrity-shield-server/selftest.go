@@ -0,0 +1,99 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	"github.com/IBM/integrity-shield/integrity-shield-server/pkg/shield"
+	"github.com/pkg/errors"
+)
+
+type selfTestCheck struct {
+	Name string
+	Run  func() error
+}
+
+// loadRequestHandlerConfig can be replaced in tests
+var loadRequestHandlerConfig = shield.LoadRequestHandlerConfig
+
+// defaultSelfTestChecks returns the checks in the order of execution.
+// The config loaded by the config check is reused by the following checks.
+func defaultSelfTestChecks() []selfTestCheck {
+	var rhconfig *k8smnfconfig.RequestHandlerConfig
+	return []selfTestCheck{
+		{
+			Name: "tls certs",
+			Run: func() error {
+				_, err := tls.LoadX509KeyPair(path.Join(tlsDir, tlsCertFile), path.Join(tlsDir, tlsKeyFile))
+				return err
+			},
+		},
+		{
+			Name: "request handler config",
+			Run: func() error {
+				var err error
+				rhconfig, err = loadRequestHandlerConfig()
+				if err != nil {
+					return err
+				}
+				if rhconfig == nil {
+					return errors.New("request handler config is not found")
+				}
+				return nil
+			},
+		},
+		{
+			Name: "keys",
+			Run: func() error {
+				if rhconfig == nil {
+					return errors.New("request handler config is not available")
+				}
+				keyPathList := append([]string{}, rhconfig.KeyPathList...)
+				for _, nk := range rhconfig.NamespacedKeyPathList {
+					keyPathList = append(keyPathList, nk.KeyPathList...)
+				}
+				for _, keyPath := range keyPathList {
+					if _, err := os.Stat(keyPath); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		},
+	}
+}
+
+// runSelfTest runs all checks and prints the result of each check.
+// It returns false if any check fails.
+func runSelfTest(checks []selfTestCheck, w io.Writer) bool {
+	ok := true
+	for _, c := range checks {
+		if err := c.Run(); err != nil {
+			fmt.Fprintf(w, "[FAIL] %s: %s\n", c.Name, err.Error())
+			ok = false
+			continue
+		}
+		fmt.Fprintf(w, "[PASS] %s\n", c.Name)
+	}
+	return ok
+}
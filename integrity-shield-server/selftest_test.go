@@ -0,0 +1,75 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+)
+
+func TestRunSelfTest(t *testing.T) {
+	passCheck := selfTestCheck{Name: "pass", Run: func() error { return nil }}
+	failCheck := selfTestCheck{Name: "fail", Run: func() error { return errors.New("unreachable") }}
+
+	out := new(bytes.Buffer)
+	if !runSelfTest([]selfTestCheck{passCheck}, out) {
+		t.Error("self test should succeed if all checks pass")
+	}
+
+	out = new(bytes.Buffer)
+	if runSelfTest([]selfTestCheck{passCheck, failCheck}, out) {
+		t.Error("self test should fail if any check fails")
+	}
+	if !strings.Contains(out.String(), "[PASS] pass") || !strings.Contains(out.String(), "[FAIL] fail: unreachable") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+}
+
+func TestSelfTestConfigChecks(t *testing.T) {
+	orgFunc := loadRequestHandlerConfig
+	defer func() { loadRequestHandlerConfig = orgFunc }()
+
+	// the config is not found, e.g. no kube config
+	loadRequestHandlerConfig = func() (*k8smnfconfig.RequestHandlerConfig, error) {
+		return nil, nil
+	}
+	out := new(bytes.Buffer)
+	if runSelfTest(defaultSelfTestChecks()[1:], out) {
+		t.Errorf("self test should fail without the config: %s", out.String())
+	}
+	if !strings.Contains(out.String(), "[FAIL] request handler config") {
+		t.Errorf("unexpected output: %s", out.String())
+	}
+
+	// the config is loaded only once
+	loaded := 0
+	loadRequestHandlerConfig = func() (*k8smnfconfig.RequestHandlerConfig, error) {
+		loaded++
+		return &k8smnfconfig.RequestHandlerConfig{}, nil
+	}
+	out = new(bytes.Buffer)
+	if !runSelfTest(defaultSelfTestChecks()[1:], out) {
+		t.Errorf("self test should succeed with the config: %s", out.String())
+	}
+	if loaded != 1 {
+		t.Errorf("the config should be loaded once, but loaded %d times", loaded)
+	}
+}
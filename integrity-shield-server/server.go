@@ -0,0 +1,67 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// timeouts of the API server; the values are durations like "30s"
+const (
+	readHeaderTimeoutEnvKey = "SERVER_READ_HEADER_TIMEOUT"
+	readTimeoutEnvKey       = "SERVER_READ_TIMEOUT"
+	writeTimeoutEnvKey      = "SERVER_WRITE_TIMEOUT"
+	idleTimeoutEnvKey       = "SERVER_IDLE_TIMEOUT"
+)
+
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 30 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+)
+
+// newServer returns the API server with timeouts so that slow or idle clients cannot hold connections forever.
+func newServer(addr string, handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		TLSConfig:         tlsConfig,
+		Handler:           handler,
+		ReadHeaderTimeout: getTimeoutFromEnv(readHeaderTimeoutEnvKey, defaultReadHeaderTimeout),
+		ReadTimeout:       getTimeoutFromEnv(readTimeoutEnvKey, defaultReadTimeout),
+		WriteTimeout:      getTimeoutFromEnv(writeTimeoutEnvKey, defaultWriteTimeout),
+		IdleTimeout:       getTimeoutFromEnv(idleTimeoutEnvKey, defaultIdleTimeout),
+	}
+}
+
+func getTimeoutFromEnv(key string, defaultTimeout time.Duration) time.Duration {
+	val := os.Getenv(key)
+	if val == "" {
+		return defaultTimeout
+	}
+	timeout, err := time.ParseDuration(val)
+	if err != nil || timeout <= 0 {
+		log.Warningf("invalid timeout `%s` in %s; use the default %s", val, key, defaultTimeout)
+		return defaultTimeout
+	}
+	return timeout
+}
@@ -0,0 +1,70 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetTimeoutFromEnv(t *testing.T) {
+	os.Setenv(readTimeoutEnvKey, "5s")
+	defer os.Unsetenv(readTimeoutEnvKey)
+	if timeout := getTimeoutFromEnv(readTimeoutEnvKey, defaultReadTimeout); timeout != 5*time.Second {
+		t.Errorf("timeout should be read from env: %s", timeout)
+	}
+	os.Setenv(readTimeoutEnvKey, "invalid")
+	if timeout := getTimeoutFromEnv(readTimeoutEnvKey, defaultReadTimeout); timeout != defaultReadTimeout {
+		t.Errorf("invalid timeout should fall back to the default: %s", timeout)
+	}
+}
+
+func TestServerClosesSlowClient(t *testing.T) {
+	os.Setenv(readHeaderTimeoutEnvKey, "200ms")
+	os.Setenv(readTimeoutEnvKey, "200ms")
+	defer os.Unsetenv(readHeaderTimeoutEnvKey)
+	defer os.Unsetenv(readTimeoutEnvKey)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %s", err.Error())
+	}
+	server := newServer(ln.Addr().String(), nil, nil)
+	go func() { _ = server.Serve(ln) }()
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("failed to connect: %s", err.Error())
+	}
+	defer conn.Close()
+	// send the request line, but never finish the headers
+	_, _ = conn.Write([]byte("POST /api/request HTTP/1.1\r\nHost: localhost\r\n"))
+
+	_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	// read until the server closes the connection
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Errorf("slow client should be cut off by the server before the client deadline: %s", err.Error())
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("slow client should be cut off per read timeout: %s", elapsed)
+	}
+}
@@ -0,0 +1,66 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// path to the CA of client certificates; mTLS is enabled only if it is set
+const clientCAFileEnvKey = "TLS_CLIENT_CA_FILE"
+
+// newServerTLSConfig returns the TLS config of the API server. If clientCAFile is set, a client certificate
+// is verified with the CA, e.g. the certificate of the API server. Otherwise one-way TLS is used.
+// The certificate is not required on the TLS handshake so that the probes and /metrics can be served
+// on the same listener; requireClientCert enforces it on the API endpoints.
+func newServerTLSConfig(pair tls.Certificate, clientCAFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{pair}, MinVersion: tls.VersionTLS12}
+	if clientCAFile == "" {
+		return tlsConfig, nil
+	}
+	caPEM, err := ioutil.ReadFile(clientCAFile)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to read client CA file `%s`", clientCAFile))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New(fmt.Sprintf("no certificates are found in client CA file `%s`", clientCAFile))
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsConfig, nil
+}
+
+// requireClientCert rejects requests without a client certificate verified by the client CA if mTLS is enabled.
+func requireClientCert(handler http.HandlerFunc, enabled bool) http.HandlerFunc {
+	if !enabled {
+		return handler
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			http.Error(w, "a client certificate is required", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
@@ -0,0 +1,126 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCert creates a certificate signed by the parent, or a self-signed CA if parent is nil.
+func newTestCert(t *testing.T, cn string, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate a key: %s", err.Error())
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if parent == nil {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		parent = tmpl
+		parentKey = key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatalf("failed to create a certificate: %s", err.Error())
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return cert, key, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestServerRejectsClientWithoutCertWhenMTLSRequired(t *testing.T) {
+	caCert, caKey, _ := newTestCert(t, "test-ca", nil, nil)
+	_, _, serverPair := newTestCert(t, "test-server", caCert, caKey)
+	_, _, clientPair := newTestCert(t, "test-client", caCert, caKey)
+	otherCACert, otherCAKey, _ := newTestCert(t, "other-ca", nil, nil)
+	_, _, otherClientPair := newTestCert(t, "other-client", otherCACert, otherCAKey)
+
+	dir, err := ioutil.TempDir("", "ishield-tls-test")
+	if err != nil {
+		t.Fatalf("failed to create a temp dir: %s", err.Error())
+	}
+	defer os.RemoveAll(dir)
+	caFile := filepath.Join(dir, "ca.crt")
+	_ = ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caCert.Raw}), 0644)
+
+	tlsConfig, err := newServerTLSConfig(serverPair, caFile)
+	if err != nil {
+		t.Fatalf("failed to configure tls: %s", err.Error())
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/request", requireClientCert(checkLiveness, true))
+	mux.HandleFunc("/health/liveness", checkLiveness)
+	server := httptest.NewUnstartedServer(mux)
+	server.TLS = tlsConfig
+	server.StartTLS()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+
+	get := func(client *http.Client, path string) int {
+		resp, err := client.Get(server.URL + path)
+		if err != nil {
+			return 0
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// without client cert, probes are served but the API is rejected
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if code := get(client, "/health/liveness"); code != http.StatusOK {
+		t.Errorf("liveness probe without a client cert should be served, but got status %d", code)
+	}
+	if code := get(client, "/api/request"); code == http.StatusOK {
+		t.Error("request without a client cert should be rejected")
+	}
+
+	// with client cert signed by another CA
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{otherClientPair}}}}
+	if code := get(client, "/api/request"); code == http.StatusOK {
+		t.Error("request with a client cert signed by an unknown CA should be rejected")
+	}
+
+	// with client cert signed by the CA
+	client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{clientPair}}}}
+	if code := get(client, "/api/request"); code != http.StatusOK {
+		t.Errorf("request with a client cert should be accepted, but got status %d", code)
+	}
+}
@@ -6,6 +6,8 @@ require (
 	github.com/IBM/integrity-shield/integrity-shield-server v0.0.0-00010101000000-000000000000
 	github.com/ghodss/yaml v1.0.0
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.11.0
+	github.com/sigstore/cosign v1.0.1
 	github.com/sigstore/k8s-manifest-sigstore v0.0.0-20210820081408-1767e96c5fe2
 	github.com/sirupsen/logrus v1.8.1
 	k8s.io/api v0.21.3
@@ -15,15 +15,39 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"net/http"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/IBM/integrity-shield/observer/pkg/observer"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+// metricsAddrEnvKey is the address to serve /metrics, e.g. ":8080". Metrics are not served if it is empty.
+const metricsAddrEnvKey = "METRICS_ADDR"
+
+func serveMetrics(addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	if err := http.ListenAndServe(addr, mux); err != nil {
+		fmt.Println("Failed to serve metrics; err: ", err.Error())
+	}
+}
+
 func main() {
+	kubeconfig := flag.String("kubeconfig", "", "path to kubeconfig file to run the observer out of the cluster")
+	flag.Parse()
+	if *kubeconfig != "" {
+		os.Setenv("KUBECONFIG", *kubeconfig)
+	}
+
+	if metricsAddr := os.Getenv(metricsAddrEnvKey); metricsAddr != "" {
+		go serveMetrics(metricsAddr)
+	}
+
 	insp := observer.NewObserver()
 	err := insp.Init()
 	if err != nil {
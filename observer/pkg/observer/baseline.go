@@ -0,0 +1,111 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// BaselineResource is a resource which is expected to be signed in the cluster
+type BaselineResource struct {
+	ApiGroup  string `json:"apiGroup,omitempty"`
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+// DriftReport is the difference between the observed resources and the baseline
+type DriftReport struct {
+	// in the baseline, but not observed
+	ExpectedMissing []BaselineResource `json:"expectedMissing"`
+	// in the baseline and observed, but the signature is not verified
+	ExpectedViolated []BaselineResource `json:"expectedViolated"`
+	// observed, but not in the baseline
+	UnexpectedPresent []BaselineResource `json:"unexpectedPresent"`
+}
+
+func (r BaselineResource) key() string {
+	return fmt.Sprintf("%s/%s/%s/%s", r.ApiGroup, r.Kind, r.Namespace, r.Name)
+}
+
+// loadBaseline returns the baseline in the observer config and in the baseline file.
+func loadBaseline(oconfig ObserverConfig) ([]BaselineResource, error) {
+	baseline := append([]BaselineResource{}, oconfig.Baseline...)
+	if oconfig.BaselineFile == "" {
+		return baseline, nil
+	}
+	baselineBytes, err := ioutil.ReadFile(oconfig.BaselineFile)
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to read baseline file `%s`", oconfig.BaselineFile))
+	}
+	var fileBaseline []BaselineResource
+	if err := yaml.Unmarshal(baselineBytes, &fileBaseline); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("failed to unmarshal baseline file `%s`", oconfig.BaselineFile))
+	}
+	return append(baseline, fileBaseline...), nil
+}
+
+// DetectDrift compares the observed results with the baseline.
+func DetectDrift(baseline []BaselineResource, results []VerifyResultDetail) DriftReport {
+	report := DriftReport{
+		ExpectedMissing:   []BaselineResource{},
+		ExpectedViolated:  []BaselineResource{},
+		UnexpectedPresent: []BaselineResource{},
+	}
+	observed := map[string]VerifyResultDetail{}
+	for _, res := range results {
+		r := baselineResourceFromResult(res)
+		if prev, found := observed[r.key()]; found && prev.Violation {
+			// a violation in any constraint is kept
+			continue
+		}
+		observed[r.key()] = res
+	}
+	expected := map[string]bool{}
+	for _, b := range baseline {
+		expected[b.key()] = true
+		res, found := observed[b.key()]
+		if !found {
+			report.ExpectedMissing = append(report.ExpectedMissing, b)
+		} else if res.Violation {
+			report.ExpectedViolated = append(report.ExpectedViolated, b)
+		}
+	}
+	for _, res := range results {
+		r := baselineResourceFromResult(res)
+		if expected[r.key()] {
+			continue
+		}
+		// report each resource once
+		expected[r.key()] = true
+		report.UnexpectedPresent = append(report.UnexpectedPresent, r)
+	}
+	return report
+}
+
+func baselineResourceFromResult(res VerifyResultDetail) BaselineResource {
+	return BaselineResource{
+		ApiGroup:  res.ApiGroup,
+		Kind:      res.Kind,
+		Namespace: res.Namespace,
+		Name:      res.Name,
+	}
+}
@@ -0,0 +1,45 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"testing"
+)
+
+func TestDetectDrift(t *testing.T) {
+	baseline := []BaselineResource{
+		{Kind: "ConfigMap", Namespace: "sample-ns", Name: "sample-cm"},
+		{Kind: "ConfigMap", Namespace: "sample-ns", Name: "missing-cm"},
+		{Kind: "ConfigMap", Namespace: "sample-ns", Name: "unsigned-cm"},
+	}
+	results := []VerifyResultDetail{
+		testResult("ConfigMap", "sample-ns", "sample-cm", "sample-registry/sample-cm:0.1", false),
+		testResult("ConfigMap", "sample-ns", "unsigned-cm", "", true),
+		testResult("ConfigMap", "sample-ns", "extra-cm", "sample-registry/extra-cm:0.1", false),
+	}
+
+	drift := DetectDrift(baseline, results)
+	if len(drift.ExpectedMissing) != 1 || drift.ExpectedMissing[0].Name != "missing-cm" {
+		t.Errorf("unexpected missing resources: %v", drift.ExpectedMissing)
+	}
+	if len(drift.ExpectedViolated) != 1 || drift.ExpectedViolated[0].Name != "unsigned-cm" {
+		t.Errorf("unexpected violated resources: %v", drift.ExpectedViolated)
+	}
+	if len(drift.UnexpectedPresent) != 1 || drift.UnexpectedPresent[0].Name != "extra-cm" {
+		t.Errorf("unexpected present resources: %v", drift.UnexpectedPresent)
+	}
+}
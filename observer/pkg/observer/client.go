@@ -0,0 +1,81 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"context"
+	"os"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/kubeutil"
+	log "github.com/sirupsen/logrus"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// getKubeConfig builds a config from the kubeconfig file in KUBECONFIG if it is set, so that the observer
+// can run out of the cluster, e.g. for debugging and CI. Otherwise the in-cluster config is used.
+func getKubeConfig() (*rest.Config, error) {
+	if kubeconfigPath := os.Getenv("KUBECONFIG"); kubeconfigPath != "" {
+		return clientcmd.BuildConfigFromFlags("", kubeconfigPath)
+	}
+	return kubeutil.GetKubeConfig()
+}
+
+// newDynamicClient builds a client with a fresh kube config. It can be replaced in tests.
+var newDynamicClient = func() (dynamic.Interface, error) {
+	kubeconf, err := getKubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	return dynamic.NewForConfig(kubeconf)
+}
+
+// refreshClient rebuilds the dynamic client so that the latest service account token is used.
+func (self *Observer) refreshClient() error {
+	dynamicClient, err := newDynamicClient()
+	if err != nil {
+		return err
+	}
+	self.dynamicClient = dynamicClient
+	return nil
+}
+
+// listResources lists resources of the gvr. If the API server returns 401 because the token of
+// the long-running observer has expired, the client is rebuilt and the request is retried once.
+func (self *Observer) listResources(gvr schema.GroupVersionResource, namespace string) (*unstructured.UnstructuredList, error) {
+	list := func() (*unstructured.UnstructuredList, error) {
+		if namespace == "" {
+			return self.dynamicClient.Resource(gvr).List(context.Background(), metav1.ListOptions{})
+		}
+		return self.dynamicClient.Resource(gvr).Namespace(namespace).List(context.Background(), metav1.ListOptions{})
+	}
+	resourceList, err := list()
+	if err != nil && k8serrors.IsUnauthorized(err) {
+		log.Warning("unauthorized error when listing resources, rebuilding the client; error: ", err.Error())
+		if rerr := self.refreshClient(); rerr != nil {
+			log.Error("failed to rebuild the client; error: ", rerr.Error())
+			return nil, err
+		}
+		resourceList, err = list()
+	}
+	return resourceList, err
+}
@@ -0,0 +1,115 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func newTestConfigMap(namespace, name string) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("ConfigMap")
+	obj.SetNamespace(namespace)
+	obj.SetName(name)
+	return obj
+}
+
+func TestListResourcesRefreshesClientOnUnauthorized(t *testing.T) {
+	cm := newTestConfigMap("sample-ns", "sample-cm")
+
+	// a client with an expired token
+	expiredClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), cm)
+	expiredClient.PrependReactor("list", "configmaps", func(action clienttesting.Action) (bool, runtime.Object, error) {
+		return true, nil, k8serrors.NewUnauthorized("token has expired")
+	})
+
+	orgFunc := newDynamicClient
+	defer func() { newDynamicClient = orgFunc }()
+	refreshed := false
+	newDynamicClient = func() (dynamic.Interface, error) {
+		refreshed = true
+		return dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), cm), nil
+	}
+
+	insp := &Observer{dynamicClient: expiredClient}
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "configmaps"}
+	list, err := insp.listResources(gvr, "sample-ns")
+	if err != nil {
+		t.Errorf("failed to list resources after refreshing the client: %s", err.Error())
+		return
+	}
+	if !refreshed {
+		t.Error("client should be refreshed on unauthorized error")
+	}
+	if len(list.Items) != 1 || list.Items[0].GetName() != "sample-cm" {
+		t.Errorf("unexpected list result: %v", list.Items)
+	}
+}
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test-cluster
+  cluster:
+    server: https://test-cluster.example.com:6443
+contexts:
+- name: test-context
+  context:
+    cluster: test-cluster
+    user: test-user
+current-context: test-context
+users:
+- name: test-user
+  user:
+    token: test-token
+`
+
+func TestGetKubeConfigOutOfCluster(t *testing.T) {
+	dir, err := ioutil.TempDir("", "observer-test")
+	if err != nil {
+		t.Errorf("failed to create a temp dir: %s", err.Error())
+		return
+	}
+	defer os.RemoveAll(dir)
+	kubeconfigPath := filepath.Join(dir, "kubeconfig")
+	_ = ioutil.WriteFile(kubeconfigPath, []byte(testKubeconfig), 0644)
+
+	orgValue := os.Getenv("KUBECONFIG")
+	defer os.Setenv("KUBECONFIG", orgValue)
+	os.Setenv("KUBECONFIG", kubeconfigPath)
+
+	config, err := getKubeConfig()
+	if err != nil {
+		t.Errorf("failed to load kubeconfig: %s", err.Error())
+		return
+	}
+	if config.Host != "https://test-cluster.example.com:6443" || config.BearerToken != "test-token" {
+		t.Errorf("config should be loaded from KUBECONFIG; host: %s", config.Host)
+	}
+}
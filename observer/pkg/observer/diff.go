@@ -0,0 +1,93 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import "fmt"
+
+// ObservationDiff is the difference between the results of two observation cycles
+type ObservationDiff struct {
+	Added   []VerifyResultDetail `json:"added"`
+	Removed []VerifyResultDetail `json:"removed"`
+	Changed []ObservationChange  `json:"changed"`
+}
+
+type ObservationChange struct {
+	Old           VerifyResultDetail `json:"old"`
+	New           VerifyResultDetail `json:"new"`
+	ChangedFields []string           `json:"changedFields"`
+}
+
+// DiffObservations compares two observation results keyed by group, kind, namespace and name.
+func DiffObservations(oldResults, newResults []VerifyResultDetail) ObservationDiff {
+	diff := ObservationDiff{}
+	oldMap := map[string]VerifyResultDetail{}
+	for _, r := range oldResults {
+		oldMap[observationKey(r)] = r
+	}
+	newMap := map[string]VerifyResultDetail{}
+	for _, r := range newResults {
+		newMap[observationKey(r)] = r
+	}
+
+	for _, n := range newResults {
+		o, found := oldMap[observationKey(n)]
+		if !found {
+			diff.Added = append(diff.Added, n)
+			continue
+		}
+		changedFields := getChangedFields(o, n)
+		if len(changedFields) > 0 {
+			diff.Changed = append(diff.Changed, ObservationChange{Old: o, New: n, ChangedFields: changedFields})
+		}
+	}
+	for _, o := range oldResults {
+		if _, found := newMap[observationKey(o)]; !found {
+			diff.Removed = append(diff.Removed, o)
+		}
+	}
+	return diff
+}
+
+func observationKey(r VerifyResultDetail) string {
+	return fmt.Sprintf("%s/%s/%s/%s", r.ApiGroup, r.Kind, r.Namespace, r.Name)
+}
+
+func getChangedFields(o, n VerifyResultDetail) []string {
+	changedFields := []string{}
+	if o.Violation != n.Violation {
+		changedFields = append(changedFields, "violation")
+	}
+	if o.Error != n.Error {
+		changedFields = append(changedFields, "error")
+	}
+	oldSigner, oldSigRef := getSignature(o)
+	newSigner, newSigRef := getSignature(n)
+	if oldSigner != newSigner {
+		changedFields = append(changedFields, "signer")
+	}
+	if oldSigRef != newSigRef {
+		changedFields = append(changedFields, "sigRef")
+	}
+	return changedFields
+}
+
+func getSignature(r VerifyResultDetail) (string, string) {
+	if r.VerifyResourceResult == nil {
+		return "", ""
+	}
+	return r.VerifyResourceResult.Signer, r.VerifyResourceResult.SigRef
+}
@@ -0,0 +1,65 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"testing"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+)
+
+func testResult(kind, namespace, name, sigRef string, violation bool) VerifyResultDetail {
+	return VerifyResultDetail{
+		Kind:      kind,
+		ApiGroup:  "",
+		Namespace: namespace,
+		Name:      name,
+		Violation: violation,
+		VerifyResourceResult: &k8smanifest.VerifyResourceResult{
+			Verified: !violation,
+			SigRef:   sigRef,
+		},
+	}
+}
+
+func TestDiffObservations(t *testing.T) {
+	oldResults := []VerifyResultDetail{
+		testResult("ConfigMap", "sample-ns", "cm-kept", "registry/manifest:v1", false),
+		testResult("ConfigMap", "sample-ns", "cm-removed", "registry/manifest:v1", false),
+		testResult("ConfigMap", "sample-ns", "cm-changed", "registry/manifest:v1", false),
+	}
+	newResults := []VerifyResultDetail{
+		testResult("ConfigMap", "sample-ns", "cm-kept", "registry/manifest:v1", false),
+		testResult("ConfigMap", "sample-ns", "cm-changed", "registry/manifest:v2", false),
+		testResult("ConfigMap", "sample-ns", "cm-added", "", true),
+	}
+	diff := DiffObservations(oldResults, newResults)
+
+	if len(diff.Added) != 1 || diff.Added[0].Name != "cm-added" {
+		t.Errorf("unexpected added results: %v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Name != "cm-removed" {
+		t.Errorf("unexpected removed results: %v", diff.Removed)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].New.Name != "cm-changed" {
+		t.Errorf("unexpected changed results: %v", diff.Changed)
+		return
+	}
+	if len(diff.Changed[0].ChangedFields) != 1 || diff.Changed[0].ChangedFields[0] != "sigRef" {
+		t.Errorf("unexpected changed fields: %v", diff.Changed[0].ChangedFields)
+	}
+}
@@ -0,0 +1,94 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"context"
+	"os"
+
+	k8smnfutil "github.com/sigstore/k8s-manifest-sigstore/pkg/util"
+	log "github.com/sirupsen/logrus"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	kubeclient "k8s.io/client-go/kubernetes"
+)
+
+// getExcludedNamespaces returns the namespaces which are not observed.
+// The namespace of integrity shield is excluded unless ObserveShieldResources is set,
+// so that the observer does not report its own pods, events and results.
+func getExcludedNamespaces(oconfig ObserverConfig) []string {
+	namespaces := append([]string{}, oconfig.ExcludeNamespaces...)
+	if !oconfig.ObserveShieldResources {
+		shieldNamespace := os.Getenv("POD_NAMESPACE")
+		if shieldNamespace == "" {
+			shieldNamespace = defaultPodNamespace
+		}
+		namespaces = append(namespaces, shieldNamespace)
+	}
+	return namespaces
+}
+
+// excludeResources removes the resources in the excluded namespaces.
+func excludeResources(resources []unstructured.Unstructured, excludedNamespaces []string) []unstructured.Unstructured {
+	if len(excludedNamespaces) == 0 {
+		return resources
+	}
+	filtered := []unstructured.Unstructured{}
+	for _, res := range resources {
+		ns := res.GetNamespace()
+		if ns != "" && k8smnfutil.MatchWithPatternArray(ns, excludedNamespaces) {
+			log.Debug("excluded from observation: ", res.GetKind(), " ", ns, "/", res.GetName())
+			continue
+		}
+		filtered = append(filtered, res)
+	}
+	return filtered
+}
+
+// loadTerminatingNamespaces returns the namespaces being deleted.
+func loadTerminatingNamespaces() []string {
+	config, err := getKubeConfig()
+	if err != nil {
+		return []string{}
+	}
+	clientset, err := kubeclient.NewForConfig(config)
+	if err != nil {
+		log.Error(err)
+		return []string{}
+	}
+	return getTerminatingNamespaces(clientset)
+}
+
+// getTerminatingNamespaces returns the namespaces being deleted. Resources in them are not observed,
+// because the verification fails with errors which are not related to their integrity,
+// e.g. a dry-run create is forbidden in a terminating namespace.
+func getTerminatingNamespaces(clientset kubeclient.Interface) []string {
+	namespaces, err := clientset.CoreV1().Namespaces().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Errorf("failed to list namespaces; %s", err.Error())
+		return []string{}
+	}
+	terminating := []string{}
+	for _, ns := range namespaces.Items {
+		if ns.Status.Phase == v1.NamespaceTerminating || ns.GetDeletionTimestamp() != nil {
+			log.Info("namespace is terminating and skipped in observation: ", ns.Name)
+			terminating = append(terminating, ns.Name)
+		}
+	}
+	return terminating
+}
@@ -0,0 +1,74 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"os"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestExcludeShieldResources(t *testing.T) {
+	os.Setenv("POD_NAMESPACE", "integrity-shield-operator-system")
+	defer os.Unsetenv("POD_NAMESPACE")
+
+	shieldPod := newTestConfigMap("integrity-shield-operator-system", "integrity-shield-api")
+	shieldPod.SetKind("Pod")
+	resources := []unstructured.Unstructured{
+		*shieldPod,
+		*newTestConfigMap("sample-ns", "sample-cm"),
+	}
+
+	filtered := excludeResources(resources, getExcludedNamespaces(ObserverConfig{}))
+	if len(filtered) != 1 || filtered[0].GetName() != "sample-cm" {
+		t.Errorf("pods of integrity shield should be excluded: %v", filtered)
+	}
+
+	// the exclusion can be overridden
+	filtered = excludeResources(resources, getExcludedNamespaces(ObserverConfig{ObserveShieldResources: true}))
+	if len(filtered) != 2 {
+		t.Errorf("pods of integrity shield should be observed if configured: %v", filtered)
+	}
+}
+
+func TestSkipTerminatingNamespaces(t *testing.T) {
+	now := metav1.Now()
+	clientset := fake.NewSimpleClientset(
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "sample-ns"}, Status: v1.NamespaceStatus{Phase: v1.NamespaceActive}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "deleting-ns"}, Status: v1.NamespaceStatus{Phase: v1.NamespaceTerminating}},
+		&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "deleted-ns", DeletionTimestamp: &now}},
+	)
+
+	terminating := getTerminatingNamespaces(clientset)
+	if len(terminating) != 2 {
+		t.Errorf("terminating namespaces should be detected: %v", terminating)
+	}
+
+	resources := []unstructured.Unstructured{
+		*newTestConfigMap("sample-ns", "sample-cm"),
+		*newTestConfigMap("deleting-ns", "sample-cm"),
+		*newTestConfigMap("deleted-ns", "sample-cm"),
+	}
+	filtered := excludeResources(resources, terminating)
+	if len(filtered) != 1 || filtered[0].GetNamespace() != "sample-ns" {
+		t.Errorf("resources in terminating namespaces should be skipped: %v", filtered)
+	}
+}
@@ -0,0 +1,104 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	ResultFormatJSON  = "json"
+	ResultFormatJSONL = "jsonl"
+	ResultFormatCSV   = "csv"
+)
+
+var csvHeader = []string{
+	"constraintName", "time", "namespace", "name", "kind", "apiGroup", "apiVersion",
+	"violation", "error", "signer", "sigRef", "signedTime", "message",
+}
+
+// resultLine is a result of a resource in a line of JSON lines output.
+type resultLine struct {
+	ConstraintName     string `json:"constraintName"`
+	VerifyResultDetail `json:",inline"`
+}
+
+// formatResultDetail converts observation results into the format in the observer config.
+// The default format is a single JSON object.
+func formatResultDetail(results ObservationDetailResults, format string) (string, error) {
+	switch format {
+	case "", ResultFormatJSON:
+		resByte, err := json.Marshal(results)
+		if err != nil {
+			return "", err
+		}
+		return string(resByte), nil
+	case ResultFormatJSONL:
+		buf := new(bytes.Buffer)
+		for _, cres := range results.ConstraintResults {
+			for _, res := range cres.Results {
+				lineBytes, err := json.Marshal(resultLine{ConstraintName: cres.ConstraintName, VerifyResultDetail: res})
+				if err != nil {
+					return "", err
+				}
+				buf.Write(lineBytes)
+				buf.WriteString("\n")
+			}
+		}
+		return buf.String(), nil
+	case ResultFormatCSV:
+		buf := new(bytes.Buffer)
+		w := csv.NewWriter(buf)
+		_ = w.Write(csvHeader)
+		for _, cres := range results.ConstraintResults {
+			for _, res := range cres.Results {
+				_ = w.Write(csvRecord(cres.ConstraintName, res))
+			}
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return "", errors.New(fmt.Sprintf("unsupported result format `%s`", format))
+}
+
+// csvRecord flattens a result into a row; verifyResourceResult is expanded into signer, sigRef and signedTime.
+func csvRecord(constraintName string, res VerifyResultDetail) []string {
+	signer := ""
+	sigRef := ""
+	signedTime := ""
+	if res.VerifyResourceResult != nil {
+		signer = res.VerifyResourceResult.Signer
+		sigRef = res.VerifyResourceResult.SigRef
+		if res.VerifyResourceResult.SignedTime != nil {
+			signedTime = res.VerifyResourceResult.SignedTime.UTC().Format(time.RFC3339)
+		}
+	}
+	return []string{
+		constraintName, res.Time, res.Namespace, res.Name, res.Kind, res.ApiGroup, res.ApiVersion,
+		strconv.FormatBool(res.Violation), strconv.FormatBool(res.Error), signer, sigRef, signedTime, res.Message,
+	}
+}
@@ -0,0 +1,103 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
+)
+
+func testObservationResults() ObservationDetailResults {
+	signedTime := time.Date(2021, 9, 1, 11, 0, 0, 0, time.UTC)
+	return ObservationDetailResults{
+		ConstraintResults: []ConstraintResult{
+			{
+				ConstraintName:  "configmap-constraint",
+				Violation:       true,
+				TotalViolations: 1,
+				Results: []VerifyResultDetail{
+					{
+						Time:       "2021-09-01 12:00:00",
+						Namespace:  "sample-ns",
+						Name:       "sample-cm",
+						Kind:       "ConfigMap",
+						ApiVersion: "v1",
+						Message:    "singed by a valid signer: signer@signer.com",
+						VerifyResourceResult: &k8smanifest.VerifyResourceResult{
+							InScope:    true,
+							Verified:   true,
+							Signer:     "signer@signer.com",
+							SigRef:     "sample-registry/sample-cm:0.1",
+							SignedTime: &signedTime,
+						},
+					},
+					{
+						Time:       "2021-09-01 12:00:00",
+						Namespace:  "sample-ns",
+						Name:       "unsigned-cm",
+						Kind:       "ConfigMap",
+						ApiVersion: "v1",
+						Message:    "Signature verification is required for this request, but no signature is found.",
+						Violation:  true,
+					},
+				},
+			},
+		},
+	}
+}
+
+func assertGolden(t *testing.T, goldenFile, actual string) {
+	expected, err := ioutil.ReadFile(filepath.Join("testdata", goldenFile))
+	if err != nil {
+		t.Errorf("failed to read golden file: %s", err.Error())
+		return
+	}
+	if string(expected) != actual {
+		t.Errorf("output does not match %s;\nexpected:\n%s\nactual:\n%s", goldenFile, string(expected), actual)
+	}
+}
+
+func TestFormatResultDetailCSV(t *testing.T) {
+	out, err := formatResultDetail(testObservationResults(), ResultFormatCSV)
+	if err != nil {
+		t.Errorf("failed to format results: %s", err.Error())
+		return
+	}
+	assertGolden(t, "result_detail.csv", out)
+}
+
+func TestFormatResultDetailJSONL(t *testing.T) {
+	results := testObservationResults()
+	// verifyResourceResult is omitted so that the golden file does not depend on the fields of the library
+	results.ConstraintResults[0].Results[0].VerifyResourceResult = nil
+	out, err := formatResultDetail(results, ResultFormatJSONL)
+	if err != nil {
+		t.Errorf("failed to format results: %s", err.Error())
+		return
+	}
+	assertGolden(t, "result_detail.jsonl", out)
+}
+
+func TestFormatResultDetailUnsupported(t *testing.T) {
+	if _, err := formatResultDetail(testObservationResults(), "xml"); err == nil {
+		t.Error("unsupported format should be an error")
+	}
+}
@@ -0,0 +1,86 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+// VerifyResource compares the live object with the signed manifest, so the value "before"
+// in the diff is the live one and "after" is the signed one.
+const (
+	diffValueLive   = "before"
+	diffValueSigned = "after"
+)
+
+// container image fields in pod specs of workload resources, e.g. `spec.template.spec.containers.0.image`
+var containerImageFieldPattern = regexp.MustCompile(`(^|\.)(initContainers|containers|ephemeralContainers)\.[0-9]+\.image$`)
+
+// ImageDrift is a container image which is changed from the signed manifest, e.g. patched after signing.
+type ImageDrift struct {
+	Field        string `json:"field"`
+	SignedImage  string `json:"signedImage"`
+	LiveImage    string `json:"liveImage"`
+	SignedDigest string `json:"signedDigest,omitempty"`
+	LiveDigest   string `json:"liveDigest,omitempty"`
+}
+
+// getImageDrift returns the container images in the diff between the live object and the signed manifest.
+func getImageDrift(diff *mapnode.DiffResult) []ImageDrift {
+	if diff == nil || diff.Size() == 0 {
+		return nil
+	}
+	drift := []ImageDrift{}
+	for _, item := range diff.Items {
+		if !containerImageFieldPattern.MatchString(item.Key) {
+			continue
+		}
+		signed := getDiffValueString(item.Values[diffValueSigned])
+		live := getDiffValueString(item.Values[diffValueLive])
+		drift = append(drift, ImageDrift{
+			Field:        item.Key,
+			SignedImage:  signed,
+			LiveImage:    live,
+			SignedDigest: getImageDigest(signed),
+			LiveDigest:   getImageDigest(live),
+		})
+	}
+	if len(drift) == 0 {
+		return nil
+	}
+	return drift
+}
+
+func getDiffValueString(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// getImageDigest returns the digest of an image reference like `<repo>@sha256:<hex>`, or an empty string for a tag.
+func getImageDigest(image string) string {
+	parts := strings.SplitN(image, "@", 2)
+	if len(parts) != 2 {
+		return ""
+	}
+	return parts[1]
+}
@@ -0,0 +1,45 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"testing"
+
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/mapnode"
+)
+
+func TestGetImageDrift(t *testing.T) {
+	signedImage := "registry.example.com/app@sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	liveImage := "registry.example.com/app@sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	signed, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app"}, "spec": {"replicas": 1, "template": {"spec": {"containers": [{"name": "app", "image": "` + signedImage + `"}]}}}}`))
+	live, _ := mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app"}, "spec": {"replicas": 2, "template": {"spec": {"containers": [{"name": "app", "image": "` + liveImage + `"}]}}}}`))
+
+	drift := getImageDrift(live.Diff(signed))
+	if len(drift) != 1 {
+		t.Errorf("only the image should be reported as image drift: %v", drift)
+		return
+	}
+	if drift[0].SignedDigest != "sha256:1111111111111111111111111111111111111111111111111111111111111111" || drift[0].LiveDigest != "sha256:2222222222222222222222222222222222222222222222222222222222222222" {
+		t.Errorf("signed and live digests should be reported: %v", drift[0])
+	}
+
+	// no image drift
+	live, _ = mapnode.NewFromBytes([]byte(`{"kind": "Deployment", "metadata": {"name": "sample-app"}, "spec": {"replicas": 2, "template": {"spec": {"containers": [{"name": "app", "image": "` + signedImage + `"}]}}}}`))
+	if drift := getImageDrift(live.Diff(signed)); drift != nil {
+		t.Errorf("diff without images should not be reported: %v", drift)
+	}
+}
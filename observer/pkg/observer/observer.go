@@ -20,9 +20,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"path/filepath"
 	"strings"
 	"time"
 
@@ -64,6 +62,8 @@ type Observer struct {
 	APIResources []groupResource
 
 	dynamicClient dynamic.Interface
+	// scope of each kind from discovery; key is "<group>/<kind>"
+	namespacedKinds map[string]bool
 }
 
 // type TargetResourceConfig struct {
@@ -79,6 +79,22 @@ type ObserverConfig struct {
 	ExportDetailResult     bool   `json:"exportDetailResult,omitempty"`
 	ResultDetailConfigName string `json:"resultDetailConfigName,omitempty"`
 	ResultDetailConfigKey  string `json:"resultDetailConfigKey,omitempty"`
+	// format of the detail result; "json" (default), "jsonl" or "csv"
+	ResultDetailFormat string `json:"resultDetailFormat,omitempty"`
+	// kinds which are always reported without namespace; "<kind>" or "<group>/<kind>"
+	ClusterScopedKinds []string `json:"clusterScopedKinds,omitempty"`
+	// resources in these namespaces are not observed
+	ExcludeNamespaces []string `json:"excludeNamespaces,omitempty"`
+	// the namespace of integrity shield is excluded from observation unless this is true
+	ObserveShieldResources bool `json:"observeShieldResources,omitempty"`
+	// resources expected to be signed; drift from them is reported in the detail result
+	Baseline     []BaselineResource `json:"baseline,omitempty"`
+	BaselineFile string             `json:"baselineFile,omitempty"`
+	// secret of the key to sign the detail result; the signature is stored with the key "<resultDetailConfigKey>.sig"
+	// the secret has the cosign private key in `cosign.key` and its password in `cosign.password`
+	ResultSigningKey *k8smnfconfig.KeyConfig `json:"resultSigningKey,omitempty"`
+	// past snapshots of the detail result kept in the configmap; only the latest is kept if not set
+	ResultRetention ResultRetention `json:"resultRetention,omitempty"`
 }
 
 type Rule struct {
@@ -98,6 +114,7 @@ type VerifyResultDetail struct {
 	Message              string                            `json:"message"`
 	Violation            bool                              `json:"violation"`
 	VerifyResourceResult *k8smanifest.VerifyResourceResult `json:"verifyResourceResult"`
+	ImageDrift           []ImageDrift                      `json:"imageDrift,omitempty"`
 }
 type ConstraintResult struct {
 	ConstraintName  string               `json:"constraintName"`
@@ -108,6 +125,7 @@ type ConstraintResult struct {
 
 type ObservationDetailResults struct {
 	ConstraintResults []ConstraintResult `json:"constraintResults"`
+	Drift             *DriftReport       `json:"drift,omitempty"`
 }
 
 // groupResource contains the APIGroup and APIResource
@@ -139,7 +157,12 @@ func NewObserver() *Observer {
 
 func (self *Observer) Init() error {
 	log.Info("init Observer....")
-	kubeconf, _ := kubeutil.GetKubeConfig()
+	kubeconf, _ := getKubeConfig()
+	if os.Getenv("KUBECONFIG") != "" && kubeconf != nil {
+		// out of the cluster; k8s-manifest-sigstore uses the same config
+		log.Info("using kubeconfig: ", os.Getenv("KUBECONFIG"))
+		kubeutil.SetKubeConfig(kubeconf)
+	}
 
 	var err error
 
@@ -187,6 +210,13 @@ func (self *Observer) Run() {
 	if err != nil {
 		log.Error("Failed to load constraints; err: ", err.Error())
 	}
+	// refresh API resources to observe CRDs installed after the last observation
+	if kubeconf, err := getKubeConfig(); err == nil {
+		if err := self.getAPIResources(kubeconf); err != nil {
+			log.Warning("Failed to refresh API resources; err: ", err.Error())
+		}
+	}
+	excludedNamespaces := append(getExcludedNamespaces(tcconfig), loadTerminatingNamespaces()...)
 	// ObservationDetailResults
 	var constraintResults []ConstraintResult
 	for _, constraint := range constraints {
@@ -194,6 +224,9 @@ func (self *Observer) Run() {
 		var violations []vrres.VerifyResult
 		var nonViolations []vrres.VerifyResult
 		narrowedGVKList := self.getPossibleProtectedGVKs(constraint.Match)
+		if rhconfig != nil {
+			narrowedGVKList = filterByApiGroup(narrowedGVKList, rhconfig.RequestFilterProfile.ApiGroups)
+		}
 		log.Debug("narrowedGVKList", narrowedGVKList)
 		ignoreFields := constraint.Parameters.IgnoreFields
 		secrets := constraint.Parameters.KeyConfigs
@@ -207,10 +240,15 @@ func (self *Observer) Run() {
 			tmpResources, _ := self.getAllResoucesByGroupResource(gResource)
 			resources = append(resources, tmpResources...)
 		}
+		resources = excludeResources(resources, excludedNamespaces)
 
 		// check all resources by verifyResource
 		ignoreFields = append(ignoreFields, rhconfig.RequestFilterProfile.IgnoreFields...)
+		ignoreFields = append(ignoreFields, rhconfig.RequestFilterProfile.GetServerManagedIgnoreFields()...)
 		results := ObserveResources(resources, constraint.Parameters.ImageRef, ignoreFields, secrets)
+		results = self.normalizeResultScope(results, tcconfig.ClusterScopedKinds)
+		// violations and non-violations follow this order
+		sortResultDetails(results)
 		for _, res := range results {
 			// simple result
 
@@ -280,9 +318,27 @@ func (self *Observer) Run() {
 	}
 
 	// export ConstraintResult
+	sortConstraintResults(constraintResults)
 	res := ObservationDetailResults{
 		ConstraintResults: constraintResults,
 	}
+	// drift from baseline
+	baseline, err := loadBaseline(tcconfig)
+	if err != nil {
+		log.Error("Failed to load baseline; err: ", err.Error())
+	} else if len(baseline) != 0 {
+		allResults := []VerifyResultDetail{}
+		for _, cres := range constraintResults {
+			allResults = append(allResults, cres.Results...)
+		}
+		drift := DetectDrift(baseline, allResults)
+		log.WithFields(log.Fields{
+			"expectedMissing":   len(drift.ExpectedMissing),
+			"expectedViolated":  len(drift.ExpectedViolated),
+			"unexpectedPresent": len(drift.UnexpectedPresent),
+		}).Info("drift from baseline")
+		res.Drift = &drift
+	}
 	_ = exportResultDetail(res, tcconfig)
 	return
 }
@@ -321,7 +377,7 @@ func checkIfInscopeConstraint(constraintName string, tcconfig Rule) bool {
 }
 
 func exportVerifyResult(vrr vrres.VerifyResourceStatusSpec, ignored bool, violated bool) error {
-	config, err := kubeutil.GetKubeConfig()
+	config, err := getKubeConfig()
 	if err != nil {
 		log.Error(err)
 		return err
@@ -400,8 +456,30 @@ func exportResultDetail(results ObservationDetailResults, oconfig ObserverConfig
 		configKey = defaultConfigKeyInConfigMap
 	}
 
+	resStr, err := formatResultDetail(results, oconfig.ResultDetailFormat)
+	if err != nil {
+		log.Error("failed to format observation results", err.Error())
+		return err
+	}
+	data := map[string]string{
+		configKey: resStr,
+	}
+	if oconfig.ResultSigningKey != nil {
+		keyPEM, password, err := loadResultSigningKey(oconfig.ResultSigningKey.KeySecretNamespace, oconfig.ResultSigningKey.KeySecretName)
+		if err != nil {
+			log.Error("failed to load the key to sign observation results", err.Error())
+			return err
+		}
+		sig, err := signResultDetail([]byte(resStr), keyPEM, password)
+		if err != nil {
+			log.Error("failed to sign observation results", err.Error())
+			return err
+		}
+		data[configKey+resultSignatureKeySuffix] = sig
+	}
+
 	// load
-	config, err := kubeutil.GetKubeConfig()
+	config, err := getKubeConfig()
 	if err != nil {
 		return nil
 	}
@@ -419,10 +497,7 @@ func exportResultDetail(results ObservationDetailResults, oconfig ObserverConfig
 				Name: configName,
 			},
 		}
-		resByte, _ := json.Marshal(results)
-		newcm.Data = map[string]string{
-			configKey: string(resByte),
-		}
+		newcm.Data = rotateResultHistory(nil, data, configKey, oconfig.ResultRetention, time.Now())
 		_, err := clientset.CoreV1().ConfigMaps(namespace).Create(context.Background(), newcm, metav1.CreateOptions{})
 		if err != nil {
 			log.Error("failed to create configmap", err.Error())
@@ -432,11 +507,14 @@ func exportResultDetail(results ObservationDetailResults, oconfig ObserverConfig
 	} else {
 		// update
 		log.Info("updating configmap ...", configName)
-		resByte, _ := json.Marshal(results)
-		cm.Data = map[string]string{
-			configKey: string(resByte),
-		}
+		cm.Data = rotateResultHistory(cm.Data, data, configKey, oconfig.ResultRetention, time.Now())
 		_, err := clientset.CoreV1().ConfigMaps(namespace).Update(context.Background(), cm, metav1.UpdateOptions{})
+		if err != nil && len(cm.Data) > len(data) {
+			// the history may make the configmap too large; write only the current snapshot
+			log.Warning("failed to update configmap with history, retrying with only the current snapshot; ", err.Error())
+			cm.Data = data
+			_, err = clientset.CoreV1().ConfigMaps(namespace).Update(context.Background(), cm, metav1.UpdateOptions{})
+		}
 		if err != nil {
 			log.Error("failed to update configmap", err.Error())
 			return err
@@ -452,13 +530,24 @@ func (self *Observer) getAPIResources(kubeconfig *rest.Config) error {
 	}
 
 	apiResourceLists, err := discoveryClient.ServerPreferredResources()
-	if err != nil {
+	if err != nil && discovery.IsGroupDiscoveryFailedError(err) {
+		// e.g. an aggregated API is unavailable; observe the other groups including CRDs
+		log.Warning("failed to discover some API groups; ", err.Error())
+	} else if err != nil {
 		return err
 	}
 
+	self.APIResources, self.namespacedKinds = newGroupResources(apiResourceLists)
+	return nil
+}
+
+// newGroupResources returns the resources which can be listed, including the instances of CRDs,
+// and the scope of each kind.
+func newGroupResources(apiResourceLists []*metav1.APIResourceList) ([]groupResource, map[string]bool) {
 	resources := []groupResource{}
+	namespacedKinds := map[string]bool{}
 	for _, apiResourceList := range apiResourceLists {
-		if len(apiResourceList.APIResources) == 0 {
+		if apiResourceList == nil || len(apiResourceList.APIResources) == 0 {
 			continue
 		}
 		gv, err := schema.ParseGroupVersion(apiResourceList.GroupVersion)
@@ -469,15 +558,19 @@ func (self *Observer) getAPIResources(kubeconfig *rest.Config) error {
 			if len(resource.Verbs) == 0 {
 				continue
 			}
+			// subresources such as `deployments/scale` are not objects
+			if strings.Contains(resource.Name, "/") || !Contains(resource.Verbs, "list") {
+				continue
+			}
 			resources = append(resources, groupResource{
 				APIGroup:    gv.Group,
 				APIVersion:  gv.Version,
 				APIResource: resource,
 			})
+			namespacedKinds[kindKey(gv.Group, resource.Kind)] = resource.Namespaced
 		}
 	}
-	self.APIResources = resources
-	return nil
+	return resources, namespacedKinds
 }
 
 func (self *Observer) getAllResoucesByGroupResource(gResourceWithTargetNS groupResourceWithTargetNS) ([]unstructured.Unstructured, error) {
@@ -493,9 +586,13 @@ func (self *Observer) getAllResoucesByGroupResource(gResourceWithTargetNS groupR
 	}
 
 	var tmpResourceList *unstructured.UnstructuredList
+	if namespaced && len(targetNSs) == 0 {
+		// all namespaces
+		targetNSs = []string{""}
+	}
 	if namespaced {
 		for _, ns := range targetNSs {
-			tmpResourceList, err = self.dynamicClient.Resource(gvr).Namespace(ns).List(context.Background(), metav1.ListOptions{})
+			tmpResourceList, err = self.listResources(gvr, ns)
 			if err != nil {
 				log.Error("failed to get tmpResourceList:", err.Error())
 				break
@@ -504,8 +601,10 @@ func (self *Observer) getAllResoucesByGroupResource(gResourceWithTargetNS groupR
 		}
 
 	} else {
-		tmpResourceList, err = self.dynamicClient.Resource(gvr).List(context.Background(), metav1.ListOptions{})
-		resources = append(resources, tmpResourceList.Items...)
+		tmpResourceList, err = self.listResources(gvr, "")
+		if err == nil {
+			resources = append(resources, tmpResourceList.Items...)
+		}
 	}
 	if err != nil {
 		// ignore RBAC error - IShield SA
@@ -530,7 +629,7 @@ func loadObserverConfig() (ObserverConfig, error) {
 		configKey = defaultConfigKeyInConfigMap
 	}
 
-	config, err := kubeutil.GetKubeConfig()
+	config, err := getKubeConfig()
 	if err != nil {
 		return empty, err
 	}
@@ -558,37 +657,10 @@ func loadObserverConfig() (ObserverConfig, error) {
 	return *tr, nil
 }
 
-func LoadKeySecret(keySecertNamespace, keySecertName string) (string, error) {
-	obj, err := kubeutil.GetResource("v1", "Secret", keySecertNamespace, keySecertName)
-	if err != nil {
-		return "", errors.Wrap(err, fmt.Sprintf("failed to get a secret `%s` in `%s` namespace", keySecertName, keySecertNamespace))
-	}
-	objBytes, _ := json.Marshal(obj.Object)
-	var secret v1.Secret
-	_ = json.Unmarshal(objBytes, &secret)
-	keyDir := fmt.Sprintf("/tmp/%s/%s/", keySecertNamespace, keySecertName)
-	log.Debug("keyDir", keyDir)
-	sumErr := []string{}
-	keyPath := ""
-	for fname, keyData := range secret.Data {
-		os.MkdirAll(keyDir, os.ModePerm)
-		fpath := filepath.Join(keyDir, fname)
-		err = ioutil.WriteFile(fpath, keyData, 0644)
-		if err != nil {
-			sumErr = append(sumErr, err.Error())
-			continue
-		}
-		keyPath = fpath
-		break
-	}
-	if keyPath == "" && len(sumErr) > 0 {
-		return "", errors.New(fmt.Sprintf("failed to save secret data as a file; %s", strings.Join(sumErr, "; ")))
-	}
-	if keyPath == "" {
-		return "", errors.New(fmt.Sprintf("no key files are found in the secret `%s` in `%s` namespace", keySecertName, keySecertNamespace))
-	}
-
-	return keyPath, nil
+// LoadKeySecret saves the key in the secret as a file and returns its path. It shares the loader of
+// the shield, so failures are logged with the secret reference and counted in the key metrics.
+func LoadKeySecret(keySecretNamespace, keySecretName string) (string, error) {
+	return k8smnfconfig.LoadKeySecret(keySecretNamespace, keySecretName)
 }
 
 //
@@ -619,7 +691,7 @@ func (self *Observer) loadConstraints() ([]ConstraintSpec, error) {
 		Version:  "v1beta1",
 		Resource: "manifestintegrityconstraint",
 	}
-	constraintList, err := self.dynamicClient.Resource(gvr).List(context.Background(), metav1.ListOptions{})
+	constraintList, err := self.listResources(gvr, "")
 	if err != nil {
 		return nil, err
 	}
@@ -650,7 +722,6 @@ func (self *Observer) getPossibleProtectedGVKs(match MatchCondition) []groupReso
 		matched, tmpGvks := checkIfRuleMatchWithGVK(match, apiResource)
 		if matched {
 			possibleProtectedGVKs = append(possibleProtectedGVKs, tmpGvks...)
-			break
 		}
 	}
 	return possibleProtectedGVKs
@@ -668,12 +739,12 @@ func checkIfRuleMatchWithGVK(match MatchCondition, apiResource groupResource) (b
 		kmatch := false
 		agmatch := false
 		if len(kinds.ApiGroups) != 0 {
-			agmatch = Contains(kinds.ApiGroups, apiResource.APIGroup)
+			agmatch = Contains(kinds.ApiGroups, "*") || Contains(kinds.ApiGroups, apiResource.APIGroup)
 		} else {
 			agmatch = true
 		}
-		if len(kinds.ApiGroups) != 0 {
-			kmatch = Contains(kinds.Kinds, apiResource.APIResource.Kind)
+		if len(kinds.Kinds) != 0 {
+			kmatch = Contains(kinds.Kinds, "*") || Contains(kinds.Kinds, apiResource.APIResource.Kind)
 		} else {
 			kmatch = true
 		}
@@ -697,6 +768,17 @@ func checkIfRuleMatchWithGVK(match MatchCondition, apiResource groupResource) (b
 	return matched, possibleProtectedGVKs
 }
 
+// filterByApiGroup removes the resources whose API group is out of the filter.
+func filterByApiGroup(gResources []groupResourceWithTargetNS, filter k8smnfconfig.ApiGroupFilter) []groupResourceWithTargetNS {
+	filtered := []groupResourceWithTargetNS{}
+	for _, gResource := range gResources {
+		if filter.Match(gResource.APIGroup) {
+			filtered = append(filtered, gResource)
+		}
+	}
+	return filtered
+}
+
 func Contains(pattern []string, value string) bool {
 	for _, p := range pattern {
 		if p == value {
@@ -711,7 +793,7 @@ func getLabelMatchedNamespace(labelSelector *metav1.LabelSelector) []string {
 	if labelSelector == nil {
 		return []string{}
 	}
-	config, err := kubeutil.GetKubeConfig()
+	config, err := getKubeConfig()
 	if err != nil {
 		return []string{}
 	}
@@ -0,0 +1,98 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"testing"
+
+	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+)
+
+func TestFilterByApiGroup(t *testing.T) {
+	gResources := []groupResourceWithTargetNS{
+		{groupResource: groupResource{APIGroup: "apps", APIVersion: "v1"}},
+		{groupResource: groupResource{APIGroup: "", APIVersion: "v1"}},
+		{groupResource: groupResource{APIGroup: "widgets.example.com", APIVersion: "v1alpha1"}},
+	}
+	filter := k8smnfconfig.ApiGroupFilter{Include: []string{"apps", "*.example.com"}}
+	filtered := filterByApiGroup(gResources, filter)
+	if len(filtered) != 2 {
+		t.Errorf("resources outside the configured groups should be skipped: %v", filtered)
+		return
+	}
+	if filtered[0].APIGroup != "apps" || filtered[1].APIGroup != "widgets.example.com" {
+		t.Errorf("unexpected resources: %v", filtered)
+	}
+}
+
+func TestObserveCRDInstances(t *testing.T) {
+	verbs := metav1.Verbs{"get", "list", "watch", "create", "update", "delete"}
+	apiResourceLists := []*metav1.APIResourceList{
+		{
+			GroupVersion: "v1",
+			APIResources: []metav1.APIResource{
+				{Name: "configmaps", Kind: "ConfigMap", Namespaced: true, Verbs: verbs},
+			},
+		},
+		{
+			// a CRD installed in the cluster
+			GroupVersion: "widgets.example.com/v1alpha1",
+			APIResources: []metav1.APIResource{
+				{Name: "widgets", Kind: "Widget", Namespaced: true, Verbs: verbs},
+				{Name: "widgets/status", Kind: "Widget", Namespaced: true, Verbs: metav1.Verbs{"get", "update"}},
+			},
+		},
+	}
+	resources, namespacedKinds := newGroupResources(apiResourceLists)
+	if len(resources) != 2 {
+		t.Errorf("subresources should not be observed: %v", resources)
+	}
+	if !namespacedKinds[kindKey("widgets.example.com", "Widget")] {
+		t.Error("scope of the CRD should be discovered")
+	}
+
+	widget := &unstructured.Unstructured{}
+	widget.SetAPIVersion("widgets.example.com/v1alpha1")
+	widget.SetKind("Widget")
+	widget.SetNamespace("sample-ns")
+	widget.SetName("sample-widget")
+	gvr := schema.GroupVersionResource{Group: "widgets.example.com", Version: "v1alpha1", Resource: "widgets"}
+	client := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(), map[schema.GroupVersionResource]string{gvr: "WidgetList"}, widget)
+	insp := &Observer{APIResources: resources, namespacedKinds: namespacedKinds, dynamicClient: client}
+
+	// all kinds in the constraint are matched, not only the first one
+	match := MatchCondition{Kinds: []Kinds{{ApiGroups: []string{"", "widgets.example.com"}, Kinds: []string{"ConfigMap", "Widget"}}}}
+	if gResources := insp.getPossibleProtectedGVKs(match); len(gResources) != 2 {
+		t.Errorf("all matched kinds should be observed: %v", gResources)
+	}
+
+	match = MatchCondition{Kinds: []Kinds{{ApiGroups: []string{"widgets.example.com"}, Kinds: []string{"Widget"}}}}
+	gResources := insp.getPossibleProtectedGVKs(match)
+	if len(gResources) != 1 || gResources[0].APIResource.Kind != "Widget" {
+		t.Errorf("only the CRD should be matched: %v", gResources)
+		return
+	}
+	instances, _ := insp.getAllResoucesByGroupResource(gResources[0])
+	if len(instances) != 1 || instances[0].GetName() != "sample-widget" {
+		t.Errorf("instances of the CRD in all namespaces should be observed: %v", instances)
+	}
+}
@@ -0,0 +1,73 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/sigstore/cosign/pkg/cosign"
+	"github.com/sigstore/k8s-manifest-sigstore/pkg/util/kubeutil"
+	v1 "k8s.io/api/core/v1"
+)
+
+// suffix of the configmap key of the detached signature of the detail result
+const resultSignatureKeySuffix = ".sig"
+
+// data entries of the secret of the signing key; the same names as the output of `cosign generate-key-pair k8s://<ns>/<name>`
+const (
+	resultSigningKeyDataKey      = "cosign.key"
+	resultSigningPasswordDataKey = "cosign.password"
+)
+
+// getSigningKeySecret can be replaced in tests
+var getSigningKeySecret = kubeutil.GetResource
+
+// loadResultSigningKey returns the private key and its password in the secret.
+// The key is kept in memory and never written to a file.
+func loadResultSigningKey(keySecretNamespace, keySecretName string) ([]byte, []byte, error) {
+	obj, err := getSigningKeySecret("v1", "Secret", keySecretNamespace, keySecretName)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, fmt.Sprintf("failed to get a secret `%s` in `%s` namespace", keySecretName, keySecretNamespace))
+	}
+	objBytes, _ := json.Marshal(obj.Object)
+	var secret v1.Secret
+	_ = json.Unmarshal(objBytes, &secret)
+	keyPEM, found := secret.Data[resultSigningKeyDataKey]
+	if !found || len(keyPEM) == 0 {
+		return nil, nil, errors.New(fmt.Sprintf("`%s` is not found in the secret `%s` in `%s` namespace", resultSigningKeyDataKey, keySecretName, keySecretNamespace))
+	}
+	return keyPEM, secret.Data[resultSigningPasswordDataKey], nil
+}
+
+// signResultDetail returns a base64 encoded detached signature of the detail result.
+// The key is a cosign private key (encrypted with the password), and the signature can be verified with
+// `cosign verify-blob --key <public key> --signature <signature>`.
+func signResultDetail(data, keyPEM, password []byte) (string, error) {
+	signer, err := cosign.LoadECDSAPrivateKey(keyPEM, password)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load the signing key")
+	}
+	sig, err := signer.SignMessage(bytes.NewReader(data))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to sign the detail result")
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
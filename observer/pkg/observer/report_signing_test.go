@@ -0,0 +1,101 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sigstore/cosign/pkg/cosign"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func TestSignResultDetail(t *testing.T) {
+	password := []byte("test-password")
+	keys, err := cosign.GenerateKeyPair(func(bool) ([]byte, error) { return password, nil })
+	if err != nil {
+		t.Fatalf("failed to generate a key pair: %s", err.Error())
+	}
+	block, _ := pem.Decode(keys.PublicBytes)
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse the public key: %s", err.Error())
+	}
+	ecdsaPub := pub.(*ecdsa.PublicKey)
+
+	// the secret generated by `cosign generate-key-pair k8s://<ns>/<name>`
+	orgFunc := getSigningKeySecret
+	defer func() { getSigningKeySecret = orgFunc }()
+	getSigningKeySecret = func(apiVersion, kind, namespace, name string) (*unstructured.Unstructured, error) {
+		if namespace != "test-ns" || name != "signing-key" {
+			return nil, errors.New("not found")
+		}
+		return &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Secret",
+			"metadata":   map[string]interface{}{"name": name, "namespace": namespace},
+			"data": map[string]interface{}{
+				"cosign.pub":      base64.StdEncoding.EncodeToString(keys.PublicBytes),
+				"cosign.password": base64.StdEncoding.EncodeToString(password),
+				"cosign.key":      base64.StdEncoding.EncodeToString(keys.PrivateBytes),
+			},
+		}}, nil
+	}
+	keyPEM, keyPassword, err := loadResultSigningKey("test-ns", "signing-key")
+	if err != nil {
+		t.Fatalf("failed to load the signing key: %s", err.Error())
+	}
+
+	results := ObservationDetailResults{
+		ConstraintResults: []ConstraintResult{
+			{ConstraintName: "configmap-constraint", Results: []VerifyResultDetail{{Kind: "ConfigMap", Name: "sample-cm"}}},
+		},
+	}
+	resStr, err := formatResultDetail(results, ResultFormatJSON)
+	if err != nil {
+		t.Fatalf("failed to format results: %s", err.Error())
+	}
+	sig, err := signResultDetail([]byte(resStr), keyPEM, keyPassword)
+	if err != nil {
+		t.Fatalf("failed to sign results: %s", err.Error())
+	}
+
+	sigBytes, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil {
+		t.Fatalf("signature should be base64 encoded: %s", err.Error())
+	}
+	digest := sha256.Sum256([]byte(resStr))
+	if !ecdsa.VerifyASN1(ecdsaPub, digest[:], sigBytes) {
+		t.Error("signature of the report should be verified with the public key")
+	}
+	tampered := sha256.Sum256([]byte(resStr + " "))
+	if ecdsa.VerifyASN1(ecdsaPub, tampered[:], sigBytes) {
+		t.Error("signature should not be verified for a modified report")
+	}
+
+	if _, err := signResultDetail([]byte(resStr), keyPEM, []byte("wrong-password")); err == nil {
+		t.Error("signing with a wrong password should fail")
+	}
+	if _, _, err := loadResultSigningKey("test-ns", "missing-key"); err == nil {
+		t.Error("loading a missing secret should fail")
+	}
+}
@@ -0,0 +1,145 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// history snapshots are stored with the key "<resultDetailConfigKey>.<time>"
+const resultHistoryTimeFormat = "20060102T150405Z"
+
+// default limit of the total size of the configmap data; a configmap must be smaller than 1MiB
+// including its metadata
+const defaultMaxResultDataSize = 1000 * 1024
+
+// ResultRetention keeps past snapshots of the detail result in the configmap.
+// History is kept only if either of the limits is set.
+type ResultRetention struct {
+	// the number of the latest snapshots kept; 0 means no limit by count
+	MaxSnapshots int `json:"maxSnapshots,omitempty"`
+	// snapshots older than this are removed, e.g. "168h"; 0 means no limit by age
+	MaxAge metav1.Duration `json:"maxAge,omitempty"`
+	// older snapshots are removed so that the total size of the data in bytes is within this limit;
+	// 0 or a value beyond the default means the default limit (1000KiB)
+	MaxTotalSize int `json:"maxTotalSize,omitempty"`
+}
+
+func (r ResultRetention) enabled() bool {
+	return r.MaxSnapshots > 0 || r.MaxAge.Duration > 0
+}
+
+func (r ResultRetention) maxTotalSize() int {
+	if r.MaxTotalSize <= 0 || r.MaxTotalSize > defaultMaxResultDataSize {
+		return defaultMaxResultDataSize
+	}
+	return r.MaxTotalSize
+}
+
+// rotateResultHistory returns the new data of the configmap; the new snapshot, and the history including
+// the new snapshot in which snapshots beyond the retention limits are removed. Snapshots are kept from the
+// latest while the total size of the data is within the size limit.
+// The current data is not modified, so the previous snapshot is kept if the configmap fails to be updated.
+func rotateResultHistory(current, snapshot map[string]string, configKey string, retention ResultRetention, now time.Time) map[string]string {
+	data := map[string]string{}
+	for k, v := range snapshot {
+		data[k] = v
+	}
+	if !retention.enabled() {
+		return data
+	}
+	// history in the current data
+	history := map[string]map[string]string{}
+	for k, v := range current {
+		ts, suffix, ok := parseResultHistoryKey(k, configKey)
+		if !ok {
+			continue
+		}
+		if _, found := history[ts]; !found {
+			history[ts] = map[string]string{}
+		}
+		history[ts][suffix] = v
+	}
+	// the new snapshot
+	nowTs := now.UTC().Format(resultHistoryTimeFormat)
+	history[nowTs] = map[string]string{}
+	for k, v := range snapshot {
+		if strings.HasPrefix(k, configKey) {
+			history[nowTs][strings.TrimPrefix(k, configKey)] = v
+		}
+	}
+
+	timestamps := []string{}
+	for ts := range history {
+		timestamps = append(timestamps, ts)
+	}
+	// the format is sortable as a string; the latest first
+	sort.Sort(sort.Reverse(sort.StringSlice(timestamps)))
+	totalSize := getDataSize(data)
+	for i, ts := range timestamps {
+		if retention.MaxSnapshots > 0 && i >= retention.MaxSnapshots {
+			break
+		}
+		if retention.MaxAge.Duration > 0 {
+			t, _ := time.Parse(resultHistoryTimeFormat, ts)
+			if now.Sub(t) > retention.MaxAge.Duration {
+				break
+			}
+		}
+		tsData := map[string]string{}
+		for suffix, v := range history[ts] {
+			tsData[configKey+"."+ts+suffix] = v
+		}
+		totalSize += getDataSize(tsData)
+		if totalSize > retention.maxTotalSize() {
+			break
+		}
+		for k, v := range tsData {
+			data[k] = v
+		}
+	}
+	return data
+}
+
+// getDataSize returns the size of the configmap data in bytes.
+func getDataSize(data map[string]string) int {
+	size := 0
+	for k, v := range data {
+		size += len(k) + len(v)
+	}
+	return size
+}
+
+// parseResultHistoryKey returns the time and the suffix, e.g. ".sig", of a history key.
+func parseResultHistoryKey(key, configKey string) (string, string, bool) {
+	if !strings.HasPrefix(key, configKey+".") {
+		return "", "", false
+	}
+	rest := strings.TrimPrefix(key, configKey+".")
+	if len(rest) < len(resultHistoryTimeFormat) {
+		return "", "", false
+	}
+	ts := rest[:len(resultHistoryTimeFormat)]
+	if _, err := time.Parse(resultHistoryTimeFormat, ts); err != nil {
+		return "", "", false
+	}
+	return ts, rest[len(resultHistoryTimeFormat):], true
+}
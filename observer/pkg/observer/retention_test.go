@@ -0,0 +1,113 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestRotateResultHistoryByCount(t *testing.T) {
+	retention := ResultRetention{MaxSnapshots: 2}
+	start := time.Date(2021, 9, 1, 12, 0, 0, 0, time.UTC)
+	var data map[string]string
+	for i := 0; i < 3; i++ {
+		snapshot := map[string]string{
+			"config.yaml":     "result",
+			"config.yaml.sig": "signature",
+		}
+		data = rotateResultHistory(data, snapshot, "config.yaml", retention, start.Add(time.Duration(i)*time.Hour))
+	}
+
+	if data["config.yaml"] != "result" || data["config.yaml.sig"] != "signature" {
+		t.Errorf("the latest snapshot should be kept: %v", data)
+	}
+	for _, key := range []string{"config.yaml.20210901T130000Z", "config.yaml.20210901T130000Z.sig", "config.yaml.20210901T140000Z", "config.yaml.20210901T140000Z.sig"} {
+		if _, found := data[key]; !found {
+			t.Errorf("`%s` should be kept in history: %v", key, data)
+		}
+	}
+	if _, found := data["config.yaml.20210901T120000Z"]; found {
+		t.Errorf("the oldest snapshot should be rotated out: %v", data)
+	}
+	if len(data) != 6 {
+		t.Errorf("unexpected keys in the data: %v", data)
+	}
+}
+
+func TestRotateResultHistoryByAge(t *testing.T) {
+	retention := ResultRetention{MaxAge: metav1.Duration{Duration: 24 * time.Hour}}
+	now := time.Date(2021, 9, 3, 12, 0, 0, 0, time.UTC)
+	current := map[string]string{
+		"config.yaml":                  "previous",
+		"config.yaml.20210901T120000Z": "old",
+		"config.yaml.20210903T000000Z": "recent",
+	}
+	data := rotateResultHistory(current, map[string]string{"config.yaml": "result"}, "config.yaml", retention, now)
+
+	if data["config.yaml"] != "result" || data["config.yaml.20210903T120000Z"] != "result" {
+		t.Errorf("the new snapshot should be kept: %v", data)
+	}
+	if data["config.yaml.20210903T000000Z"] != "recent" {
+		t.Errorf("recent snapshot should be kept: %v", data)
+	}
+	if _, found := data["config.yaml.20210901T120000Z"]; found {
+		t.Errorf("old snapshot should be rotated out: %v", data)
+	}
+	if current["config.yaml"] != "previous" || len(current) != 3 {
+		t.Errorf("the current data should not be modified: %v", current)
+	}
+
+	// no history without retention
+	data = rotateResultHistory(current, map[string]string{"config.yaml": "result"}, "config.yaml", ResultRetention{}, now)
+	if len(data) != 1 {
+		t.Errorf("history should not be kept without retention: %v", data)
+	}
+}
+
+func TestRotateResultHistoryBySize(t *testing.T) {
+	// only maxAge is set; the total size must still be limited
+	retention := ResultRetention{MaxAge: metav1.Duration{Duration: 24 * time.Hour}}
+	start := time.Date(2021, 9, 1, 0, 0, 0, 0, time.UTC)
+	result := strings.Repeat("x", 200*1024)
+	var data map[string]string
+	for i := 0; i < 10; i++ {
+		data = rotateResultHistory(data, map[string]string{"config.yaml": result}, "config.yaml", retention, start.Add(time.Duration(i)*time.Hour))
+	}
+	if size := getDataSize(data); size > defaultMaxResultDataSize {
+		t.Errorf("the total size %d should be within the limit %d", size, defaultMaxResultDataSize)
+	}
+	if data["config.yaml"] != result || data["config.yaml.20210901T090000Z"] != result {
+		t.Errorf("the latest snapshot should be kept: %d keys", len(data))
+	}
+	if _, found := data["config.yaml.20210901T000000Z"]; found {
+		t.Error("the oldest snapshot should be removed by the size limit")
+	}
+
+	// a smaller limit
+	retention.MaxTotalSize = 500 * 1024
+	data = rotateResultHistory(data, map[string]string{"config.yaml": result}, "config.yaml", retention, start.Add(10*time.Hour))
+	if size := getDataSize(data); size > retention.MaxTotalSize {
+		t.Errorf("the total size %d should be within the limit %d", size, retention.MaxTotalSize)
+	}
+	if len(data) != 2 {
+		t.Errorf("only the new snapshot and its history entry should be kept, but got %d keys", len(data))
+	}
+}
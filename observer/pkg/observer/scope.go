@@ -0,0 +1,52 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	log "github.com/sirupsen/logrus"
+)
+
+func kindKey(group, kind string) string {
+	return group + "/" + kind
+}
+
+// isNamespacedKind returns the scope of a kind. The configured cluster-scoped kinds take
+// precedence over the scope cached from the discovery API.
+func (self *Observer) isNamespacedKind(group, kind string, clusterScopedKinds []string) bool {
+	if Contains(clusterScopedKinds, kind) || Contains(clusterScopedKinds, kindKey(group, kind)) {
+		return false
+	}
+	if namespaced, found := self.namespacedKinds[kindKey(group, kind)]; found {
+		return namespaced
+	}
+	return true
+}
+
+// normalizeResultScope removes the namespace from the results of cluster-scoped kinds
+// so that they are keyed only by kind and name.
+func (self *Observer) normalizeResultScope(results []VerifyResultDetail, clusterScopedKinds []string) []VerifyResultDetail {
+	for i, res := range results {
+		namespaced := self.isNamespacedKind(res.ApiGroup, res.Kind, clusterScopedKinds)
+		if !namespaced && res.Namespace != "" {
+			results[i].Namespace = ""
+		}
+		if namespaced && res.Namespace == "" {
+			log.Warning("namespace is empty in the result of a namespaced kind: ", res.Kind, " ", res.Name)
+		}
+	}
+	return results
+}
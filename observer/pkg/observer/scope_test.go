@@ -0,0 +1,47 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"testing"
+)
+
+func TestNormalizeResultScope(t *testing.T) {
+	insp := &Observer{
+		namespacedKinds: map[string]bool{
+			kindKey("", "ConfigMap"):                true,
+			kindKey("example.com", "ClusterWidget"): false,
+		},
+	}
+
+	results := []VerifyResultDetail{
+		{ApiGroup: "example.com", Kind: "ClusterWidget", Namespace: "sample-ns", Name: "widget"},
+		{ApiGroup: "", Kind: "ConfigMap", Namespace: "sample-ns", Name: "sample-cm"},
+		{ApiGroup: "example.com", Kind: "UnknownKind", Namespace: "sample-ns", Name: "unknown"},
+	}
+	results = insp.normalizeResultScope(results, []string{"example.com/UnknownKind"})
+
+	if results[0].Namespace != "" {
+		t.Errorf("cluster-scoped CRD from discovery should not have namespace: %s", results[0].Namespace)
+	}
+	if results[1].Namespace != "sample-ns" {
+		t.Errorf("namespaced kind should keep namespace: %s", results[1].Namespace)
+	}
+	if results[2].Namespace != "" {
+		t.Errorf("configured cluster-scoped kind should not have namespace: %s", results[2].Namespace)
+	}
+}
@@ -0,0 +1,50 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"sort"
+)
+
+// The results are sorted by GVK, namespace and name, so that the outputs of two observations can be compared by diff.
+
+func sortResultDetails(results []VerifyResultDetail) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return lessResource(
+			[]string{results[i].ApiGroup, results[i].ApiVersion, results[i].Kind, results[i].Namespace, results[i].Name},
+			[]string{results[j].ApiGroup, results[j].ApiVersion, results[j].Kind, results[j].Namespace, results[j].Name},
+		)
+	})
+}
+
+func sortConstraintResults(results []ConstraintResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].ConstraintName < results[j].ConstraintName
+	})
+	for _, cres := range results {
+		sortResultDetails(cres.Results)
+	}
+}
+
+func lessResource(a, b []string) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+//
+// Copyright 2020 IBM Corporation
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+//
+
+package observer
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSortConstraintResults(t *testing.T) {
+	expected := []VerifyResultDetail{
+		{ApiGroup: "", ApiVersion: "v1", Kind: "ConfigMap", Namespace: "ns-a", Name: "cm-a"},
+		{ApiGroup: "", ApiVersion: "v1", Kind: "ConfigMap", Namespace: "ns-a", Name: "cm-b"},
+		{ApiGroup: "", ApiVersion: "v1", Kind: "ConfigMap", Namespace: "ns-b", Name: "cm-a"},
+		{ApiGroup: "", ApiVersion: "v1", Kind: "Secret", Namespace: "ns-a", Name: "secret-a"},
+		{ApiGroup: "apps", ApiVersion: "v1", Kind: "Deployment", Namespace: "ns-a", Name: "deploy-a"},
+		{ApiGroup: "rbac.authorization.k8s.io", ApiVersion: "v1", Kind: "ClusterRole", Name: "role-a"},
+	}
+
+	for n := 0; n < 10; n++ {
+		shuffled := append([]VerifyResultDetail{}, expected...)
+		rand.Shuffle(len(shuffled), func(i, j int) { shuffled[i], shuffled[j] = shuffled[j], shuffled[i] })
+		results := []ConstraintResult{
+			{ConstraintName: "deployment-constraint", Results: shuffled},
+			{ConstraintName: "configmap-constraint"},
+		}
+		sortConstraintResults(results)
+		if results[0].ConstraintName != "configmap-constraint" {
+			t.Errorf("constraint results should be sorted by name: %s", results[0].ConstraintName)
+		}
+		if !reflect.DeepEqual(results[1].Results, expected) {
+			t.Errorf("results should be sorted by GVK, namespace and name: %v", results[1].Results)
+			return
+		}
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"time"
 
 	k8smnfconfig "github.com/IBM/integrity-shield/integrity-shield-server/pkg/config"
+	ishield "github.com/IBM/integrity-shield/integrity-shield-server/pkg/shield"
 	"github.com/sigstore/k8s-manifest-sigstore/pkg/k8smanifest"
 	log "github.com/sirupsen/logrus"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -34,6 +35,10 @@ func ObserveResources(resources []unstructured.Unstructured, imageRef string, ig
 		log.Debug("Observed Resource:", resource.GetAPIVersion(), resource.GetKind(), resource.GetNamespace(), resource.GetName())
 		vo := &k8smanifest.VerifyResourceOption{}
 		vo.IgnoreFields = ignoreFields
+		// status of ConstraintTemplates and constraints written by Gatekeeper
+		if binding, ok := ishield.GetGatekeeperIgnoreField(resource); ok {
+			vo.IgnoreFields = append(append(k8smanifest.ObjectFieldBindingList{}, ignoreFields...), binding)
+		}
 		vo.CheckDryRunForApply = true
 		vo.ImageRef = imageRef
 		vo.Provenance = true
@@ -102,12 +107,15 @@ func ObserveResources(resources []unstructured.Unstructured, imageRef string, ig
 			Time: time.Now().Format(timeFormat),
 			// Resource:             resource,
 			Kind:                 resource.GroupVersionKind().Kind,
+			ApiGroup:             resource.GetObjectKind().GroupVersionKind().Group,
+			ApiVersion:           resource.GetObjectKind().GroupVersionKind().Version,
 			Name:                 resource.GetName(),
 			Namespace:            resource.GetNamespace(),
 			Error:                false,
 			Message:              resultMsg,
 			VerifyResourceResult: result,
 			Violation:            violation,
+			ImageDrift:           getImageDrift(result.Diff),
 		})
 	}
 	return results